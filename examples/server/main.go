@@ -1,8 +1,12 @@
 package main
 
 import (
+	"crypto/ed25519"
+	"crypto/subtle"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"net/http"
@@ -10,8 +14,12 @@ import (
 	"os/exec"
 	"path"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/northbright/lottery-go/lottery"
+	"github.com/northbright/lottery-go/lottery/sqlitestore"
 )
 
 type Config struct {
@@ -19,6 +27,48 @@ type Config struct {
 	Addr string `json:"addr"`
 	// LotteryName is the lottery name.
 	LotteryName string `json:"lottery_name"`
+	// GroupCap caps the amount of winners from the same group(optional).
+	GroupCap *lottery.GroupCap `json:"group_cap,omitempty"`
+	// ClaimTimeout is the time in seconds for a winner to claim the prize(optional).
+	// A pending winner is forfeited and replaced after the timeout.
+	ClaimTimeout int `json:"claim_timeout,omitempty"`
+	// RevokePolicies maps revoke reason to policy(optional).
+	// e.g. {"absent": "exclude_lottery", "late": "exclude_prize"}.
+	RevokePolicies map[string]string `json:"revoke_policies,omitempty"`
+	// Users maps user name to password for HTTP basic authentication(optional).
+	// All requests need to be authenticated if it's set.
+	// The user name is recorded as the actor in the audit log.
+	Users map[string]string `json:"users,omitempty"`
+	// DataDir is the dir of the lottery data(optional).
+	// Default dir is lottery.AppDataDir.
+	DataDir string `json:"data_dir,omitempty"`
+	// Store is the backend to save the lottery data: "json"(default) or "sqlite".
+	Store string `json:"store,omitempty"`
+	// SQLiteFile is the SQLite database file of the "sqlite" store.
+	// Default file is "lottery.db" in the data dir.
+	SQLiteFile string `json:"sqlite_file,omitempty"`
+	// Backups is the max amount of backups kept by the "json" store(optional).
+	// Default is lottery.DefaultBackups. -1 means no backups.
+	Backups int `json:"backups,omitempty"`
+	// Signing signs the saved data(optional).
+	Signing *SigningConfig `json:"signing,omitempty"`
+	// ParticipantsImport is the import spec of the participants CSV(optional).
+	ParticipantsImport lottery.ImportSpec `json:"participants_import,omitempty"`
+	// PrizesImport is the import spec of the prizes CSV(optional).
+	PrizesImport lottery.ImportSpec `json:"prizes_import,omitempty"`
+}
+
+// SigningConfig is the key of the signature over the saved data.
+// Key files contain hex encoded keys.
+type SigningConfig struct {
+	// Alg is the signing algorithm: "hmac-sha256" or "ed25519".
+	Alg string `json:"alg"`
+	// KeyFile is the secret key file of "hmac-sha256",
+	// or the private key(or seed) file of "ed25519".
+	KeyFile string `json:"key_file,omitempty"`
+	// PublicKeyFile is the public key file of "ed25519".
+	// Saved data can be verified but not signed if KeyFile is not set.
+	PublicKeyFile string `json:"public_key_file,omitempty"`
 }
 
 var (
@@ -27,7 +77,13 @@ var (
 	participantsCSV  string
 	prizesCSV        string
 	blacklistsJSON   string
+	rulesJSON        string
 	lott             *lottery.Lottery
+	lotteryName      string
+	workspace        *lottery.Workspace // Workspace of the lottery data.
+	jsonStore        bool               // Lottery data is saved as JSON files in the workspace.
+	signerOptions    []lottery.Option   // Options to load and re-sign saved lotteries.
+	users            map[string]string  // Users for HTTP basic authentication.
 )
 
 // prizes returns the prizes.
@@ -74,6 +130,49 @@ func prizes(w http.ResponseWriter, r *http.Request) {
 	prizes = lott.Prizes(true)
 }
 
+// compactJournal replaces the events of the journal with a snapshot of the current state.
+func compactJournal(w http.ResponseWriter, r *http.Request) {
+	type Response struct {
+		Success bool   `json:"success"`
+		ErrMsg  string `json:"err_msg,omitempty"`
+	}
+
+	var (
+		errMsg string
+	)
+
+	defer func() {
+		resp := Response{}
+
+		if errMsg == "" {
+			resp.Success = true
+		} else {
+			resp.Success = false
+			resp.ErrMsg = errMsg
+			log.Printf("compactJournal(): error: %v", errMsg)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "    ")
+		if err := enc.Encode(&resp); err != nil {
+			log.Printf("compactJournal() encode JSON error: %v", err)
+			return
+		}
+	}()
+
+	if r.Method != "POST" {
+		errMsg = fmt.Sprintf("compactJournal(): HTTP method is NOT POST(%v)", r.Method)
+		return
+	}
+
+	if err := lott.CompactJournal(); err != nil {
+		errMsg = fmt.Sprintf("compactJournal(): CompactJournal() error: %v", err)
+		return
+	}
+}
+
 // availableParticipants returns the available participants for given prize no.
 func availableParticipants(w http.ResponseWriter, r *http.Request) {
 	type Request struct {
@@ -138,16 +237,16 @@ func winners(w http.ResponseWriter, r *http.Request) {
 	}
 
 	type Response struct {
-		Success bool                  `json:"success"`
-		ErrMsg  string                `json:"err_msg,omitempty"`
-		PrizeNo int                   `json:"prize_no"`
-		Winners []lottery.Participant `json:"winners"`
+		Success bool             `json:"success"`
+		ErrMsg  string           `json:"err_msg,omitempty"`
+		PrizeNo int              `json:"prize_no"`
+		Winners []lottery.Winner `json:"winners"`
 	}
 
 	var (
 		errMsg  string
 		req     Request
-		winners []lottery.Participant
+		winners []lottery.Winner
 	)
 
 	defer func() {
@@ -190,20 +289,21 @@ func winners(w http.ResponseWriter, r *http.Request) {
 // draw draws a prize and returns the winners.
 func draw(w http.ResponseWriter, r *http.Request) {
 	type Request struct {
-		PrizeNo int `json:"prize_no"`
+		PrizeNo int    `json:"prize_no"`
+		Reason  string `json:"reason"`
 	}
 
 	type Response struct {
-		Success bool                  `json:"success"`
-		ErrMsg  string                `json:"err_msg,omitempty"`
-		PrizeNo int                   `json:"prize_no"`
-		Winners []lottery.Participant `json:"winners"`
+		Success bool             `json:"success"`
+		ErrMsg  string           `json:"err_msg,omitempty"`
+		PrizeNo int              `json:"prize_no"`
+		Winners []lottery.Winner `json:"winners"`
 	}
 
 	var (
 		errMsg  string
 		req     Request
-		winners []lottery.Participant
+		winners []lottery.Winner
 	)
 
 	defer func() {
@@ -241,14 +341,160 @@ func draw(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	winners, err := lott.Draw(req.PrizeNo)
+	winners, err := actor(r, req.Reason).Draw(req.PrizeNo)
 	if err != nil {
 		errMsg = fmt.Sprintf("draw(): Draw() error: %v", err)
 		return
 	}
 
-	if err := lott.SaveToFile(); err != nil {
-		errMsg = fmt.Sprintf("draw(): SaveToFile() error: %v", err)
+	if err := lott.SaveToStore(); err != nil {
+		errMsg = fmt.Sprintf("draw(): SaveToStore() error: %v", err)
+		return
+	}
+}
+
+// drawStratified draws a prize with the amount allocated across the strata of an attribute.
+func drawStratified(w http.ResponseWriter, r *http.Request) {
+	type Request struct {
+		PrizeNo   int    `json:"prize_no"`
+		Attribute string `json:"attribute"`
+		Reason    string `json:"reason"`
+	}
+
+	type Response struct {
+		Success   bool              `json:"success"`
+		ErrMsg    string            `json:"err_msg,omitempty"`
+		PrizeNo   int               `json:"prize_no"`
+		Attribute string            `json:"attribute"`
+		Winners   []lottery.Winner  `json:"winners"`
+		Strata    []lottery.Stratum `json:"strata"`
+	}
+
+	var (
+		errMsg  string
+		req     Request
+		winners []lottery.Winner
+		strata  []lottery.Stratum
+	)
+
+	defer func() {
+		resp := Response{}
+
+		if errMsg == "" {
+			resp.Success = true
+		} else {
+			resp.Success = false
+			resp.ErrMsg = errMsg
+			log.Printf("drawStratified(): error: %v", errMsg)
+		}
+
+		resp.PrizeNo = req.PrizeNo
+		resp.Attribute = req.Attribute
+		resp.Winners = winners
+		resp.Strata = strata
+
+		w.Header().Set("Content-Type", "application/json")
+
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "    ")
+		if err := enc.Encode(&resp); err != nil {
+			log.Printf("drawStratified() encode JSON error: %v", err)
+			return
+		}
+	}()
+
+	if r.Method != "POST" {
+		errMsg = fmt.Sprintf("drawStratified(): HTTP method is NOT POST(%v)", r.Method)
+		return
+	}
+
+	dec := json.NewDecoder(r.Body)
+	if err := dec.Decode(&req); err != nil {
+		errMsg = fmt.Sprintf("drawStratified(): decode JSON error: %v", err)
+		return
+	}
+
+	winners, strata, err := actor(r, req.Reason).DrawStratified(req.PrizeNo, req.Attribute)
+	if err != nil {
+		errMsg = fmt.Sprintf("drawStratified(): DrawStratified() error: %v", err)
+		return
+	}
+
+	if err := lott.SaveToStore(); err != nil {
+		errMsg = fmt.Sprintf("drawStratified(): SaveToStore() error: %v", err)
+		return
+	}
+}
+
+// drawBatch draws the given amount of the remaining places of a prize.
+func drawBatch(w http.ResponseWriter, r *http.Request) {
+	type Request struct {
+		PrizeNo int    `json:"prize_no"`
+		Amount  int    `json:"amount"`
+		Reason  string `json:"reason"`
+	}
+
+	type Response struct {
+		Success bool             `json:"success"`
+		ErrMsg  string           `json:"err_msg,omitempty"`
+		PrizeNo int              `json:"prize_no"`
+		Amount  int              `json:"amount"`
+		Round   int              `json:"round"`
+		Winners []lottery.Winner `json:"winners"`
+	}
+
+	var (
+		errMsg  string
+		req     Request
+		round   int
+		winners []lottery.Winner
+	)
+
+	defer func() {
+		resp := Response{}
+
+		if errMsg == "" {
+			resp.Success = true
+		} else {
+			resp.Success = false
+			resp.ErrMsg = errMsg
+			log.Printf("drawBatch(): error: %v", errMsg)
+		}
+
+		resp.PrizeNo = req.PrizeNo
+		resp.Amount = req.Amount
+		resp.Round = round
+		resp.Winners = winners
+
+		w.Header().Set("Content-Type", "application/json")
+
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "    ")
+		if err := enc.Encode(&resp); err != nil {
+			log.Printf("drawBatch() encode JSON error: %v", err)
+			return
+		}
+	}()
+
+	if r.Method != "POST" {
+		errMsg = fmt.Sprintf("drawBatch(): HTTP method is NOT POST(%v)", r.Method)
+		return
+	}
+
+	dec := json.NewDecoder(r.Body)
+	if err := dec.Decode(&req); err != nil {
+		errMsg = fmt.Sprintf("drawBatch(): decode JSON error: %v", err)
+		return
+	}
+
+	winners, round, err := actor(r, req.Reason).DrawBatch(req.PrizeNo, req.Amount)
+	if err != nil {
+		errMsg = fmt.Sprintf("drawBatch(): DrawBatch() error: %v", err)
+		return
+	}
+
+	if err := lott.SaveToStore(); err != nil {
+		errMsg = fmt.Sprintf("drawBatch(): SaveToStore() error: %v", err)
 		return
 	}
 }
@@ -258,6 +504,7 @@ func revoke(w http.ResponseWriter, r *http.Request) {
 	type Request struct {
 		PrizeNo        int                   `json:"prize_no"`
 		RevokedWinners []lottery.Participant `json:"revoked_winners"`
+		Reason         string                `json:"reason"`
 	}
 
 	type Response struct {
@@ -265,6 +512,8 @@ func revoke(w http.ResponseWriter, r *http.Request) {
 		ErrMsg         string                `json:"err_msg,omitempty"`
 		PrizeNo        int                   `json:"prize_no"`
 		RevokedWinners []lottery.Participant `json:"revoked_winners"`
+		Reason         string                `json:"reason"`
+		Policy         string                `json:"policy"`
 	}
 
 	var (
@@ -285,6 +534,8 @@ func revoke(w http.ResponseWriter, r *http.Request) {
 
 		resp.PrizeNo = req.PrizeNo
 		resp.RevokedWinners = req.RevokedWinners
+		resp.Reason = req.Reason
+		resp.Policy = lott.RevokePolicy(req.Reason)
 
 		w.Header().Set("Content-Type", "application/json")
 
@@ -307,13 +558,13 @@ func revoke(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := lott.Revoke(req.PrizeNo, req.RevokedWinners); err != nil {
+	if err := actor(r, "").Revoke(req.PrizeNo, req.RevokedWinners, req.Reason); err != nil {
 		errMsg = fmt.Sprintf("revoke(): Revoke() error: %v", err)
 		return
 	}
 
-	if err := lott.SaveToFile(); err != nil {
-		errMsg = fmt.Sprintf("revoke(): SaveToFile() error: %v", err)
+	if err := lott.SaveToStore(); err != nil {
+		errMsg = fmt.Sprintf("revoke(): SaveToStore() error: %v", err)
 		return
 	}
 }
@@ -321,22 +572,23 @@ func revoke(w http.ResponseWriter, r *http.Request) {
 // redraw re-draws a prize with given prize no and amount.
 func redraw(w http.ResponseWriter, r *http.Request) {
 	type Request struct {
-		PrizeNo int `json:"prize_no"`
-		Amount  int `json:"amount"`
+		PrizeNo int    `json:"prize_no"`
+		Amount  int    `json:"amount"`
+		Reason  string `json:"reason"`
 	}
 
 	type Response struct {
-		Success bool                  `json:"success"`
-		ErrMsg  string                `json:"err_msg,omitempty"`
-		PrizeNo int                   `json:"prize_no"`
-		Amount  int                   `json:"amount"`
-		Winners []lottery.Participant `json:"winners"`
+		Success bool             `json:"success"`
+		ErrMsg  string           `json:"err_msg,omitempty"`
+		PrizeNo int              `json:"prize_no"`
+		Amount  int              `json:"amount"`
+		Winners []lottery.Winner `json:"winners"`
 	}
 
 	var (
 		errMsg  string
 		req     Request
-		winners []lottery.Participant
+		winners []lottery.Winner
 	)
 
 	defer func() {
@@ -375,109 +627,1351 @@ func redraw(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	winners, err := lott.Redraw(req.PrizeNo, req.Amount)
+	winners, err := actor(r, req.Reason).Redraw(req.PrizeNo, req.Amount)
 	if err != nil {
 		errMsg = fmt.Sprintf("redraw(): Redraw() error: %v", err)
 		return
 	}
 
-	if err := lott.SaveToFile(); err != nil {
-		errMsg = fmt.Sprintf("redraw(): SaveToFile() error: %v", err)
+	if err := lott.SaveToStore(); err != nil {
+		errMsg = fmt.Sprintf("redraw(): SaveToStore() error: %v", err)
 		return
 	}
 }
 
-// GetCurrentExecDir gets the current executable path.
-func GetCurrentExecDir() (dir string, err error) {
-	p, err := exec.LookPath(os.Args[0])
-	if err != nil {
-		return "", err
+// replace revokes winners of a prize and draws the replacements in one operation.
+func replace(w http.ResponseWriter, r *http.Request) {
+	type Request struct {
+		PrizeNo    int      `json:"prize_no"`
+		RevokedIDs []string `json:"revoked_ids"`
+		Reason     string   `json:"reason"`
 	}
 
-	absPath, err := filepath.Abs(p)
-	if err != nil {
-		return "", err
+	type Response struct {
+		Success      bool             `json:"success"`
+		ErrMsg       string           `json:"err_msg,omitempty"`
+		PrizeNo      int              `json:"prize_no"`
+		RevokedIDs   []string         `json:"revoked_ids"`
+		Reason       string           `json:"reason"`
+		Replacements []lottery.Winner `json:"replacements"`
 	}
 
-	dir = filepath.Dir(absPath)
-	return dir, nil
-}
+	var (
+		errMsg       string
+		req          Request
+		replacements []lottery.Winner
+	)
 
-func loadConfig() (Config, error) {
-	config := Config{}
+	defer func() {
+		resp := Response{}
 
-	f := path.Join(serverRoot, "settings/config.json")
+		if errMsg == "" {
+			resp.Success = true
+		} else {
+			resp.Success = false
+			resp.ErrMsg = errMsg
+			log.Printf("replace(): error: %v", errMsg)
+		}
 
-	buf, err := ioutil.ReadFile(f)
-	if err != nil {
-		return config, err
+		resp.PrizeNo = req.PrizeNo
+		resp.RevokedIDs = req.RevokedIDs
+		resp.Reason = req.Reason
+		resp.Replacements = replacements
+
+		w.Header().Set("Content-Type", "application/json")
+
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "    ")
+		if err := enc.Encode(&resp); err != nil {
+			log.Printf("replace() encode JSON error: %v", err)
+			return
+		}
+	}()
+
+	if r.Method != "POST" {
+		errMsg = fmt.Sprintf("replace(): HTTP method is NOT POST(%v)", r.Method)
+		return
 	}
 
-	if err := json.Unmarshal(buf, &config); err != nil {
-		return config, err
+	dec := json.NewDecoder(r.Body)
+	if err := dec.Decode(&req); err != nil {
+		errMsg = fmt.Sprintf("replace(): decode JSON error: %v", err)
+		return
 	}
-	return config, nil
-}
 
-func init() {
-	// Get absolute path of server root(current executable).
-	serverRoot, _ = GetCurrentExecDir()
-	// Get static folder path.
-	staticFolderPath = path.Join(serverRoot, "./statics/dist/spa")
-	log.Printf("staticFolderPath: %v", staticFolderPath)
+	replacements, err := actor(r, "").Replace(req.PrizeNo, req.RevokedIDs, req.Reason)
+	if err != nil {
+		errMsg = fmt.Sprintf("replace(): Replace() error: %v", err)
+		return
+	}
 
-	participantsCSV = path.Join(serverRoot, "./settings/participants.csv")
-	prizesCSV = path.Join(serverRoot, "./settings/prizes.csv")
-	blacklistsJSON = path.Join(serverRoot, "./settings/blacklists.json")
+	if err := lott.SaveToStore(); err != nil {
+		errMsg = fmt.Sprintf("replace(): SaveToStore() error: %v", err)
+		return
+	}
 }
 
-func main() {
-	// Load config.
-	config, err := loadConfig()
-	if err != nil {
-		log.Printf("loadConfig() error: %v", err)
-		return
+// alternates returns the remaining alternates of a prize.
+func alternates(w http.ResponseWriter, r *http.Request) {
+	type Request struct {
+		PrizeNo int `json:"prize_no"`
 	}
 
-	log.Printf("load config successfully. config: %v", config)
+	type Response struct {
+		Success    bool                  `json:"success"`
+		ErrMsg     string                `json:"err_msg,omitempty"`
+		PrizeNo    int                   `json:"prize_no"`
+		Alternates []lottery.Participant `json:"alternates"`
+	}
 
-	// Create a lottery.
-	lott = lottery.New(config.LotteryName)
+	var (
+		errMsg     string
+		req        Request
+		alternates []lottery.Participant
+	)
 
-	// Check if data file is already saved.
-	if lott.DataFileExists() {
-		// The lottery started and saved the data.
-		// Load the data and continue.
-		log.Printf("saved data file found")
-		if err := lott.LoadFromFile(); err != nil {
-			log.Printf("load data file error: %v", err)
-			return
-		}
-	} else {
-		// 1st run for the lottery.
-		// Load participants.
-		if err := lott.LoadParticipantsCSVFile(participantsCSV); err != nil {
-			log.Printf("load participants CSV error: %v", err)
-			return
-		}
-		log.Printf("load participants CSV successfully")
-		log.Printf("participants: %v", lott.Participants())
+	defer func() {
+		resp := Response{}
 
-		// Load prizes.
-		if err := lott.LoadPrizesCSVFile(prizesCSV); err != nil {
-			log.Printf("load prizes CSV error: %v", err)
-			return
+		if errMsg == "" {
+			resp.Success = true
+		} else {
+			resp.Success = false
+			resp.ErrMsg = errMsg
+			log.Printf("alternates(): error: %v", errMsg)
 		}
-		log.Printf("load prizes CSV successfully")
-		log.Printf("prizes: %v", lott.Prizes(true))
 
-		// Load blacklists.
-		if err := lott.LoadBlacklistsJSONFile(blacklistsJSON); err != nil {
-			log.Printf("load blacklists JSON error: %v", err)
+		resp.PrizeNo = req.PrizeNo
+		resp.Alternates = alternates
+
+		w.Header().Set("Content-Type", "application/json")
+
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "    ")
+		if err := enc.Encode(&resp); err != nil {
+			log.Printf("alternates() encode JSON error: %v", err)
+			return
+		}
+	}()
+
+	if r.Method != "POST" {
+		errMsg = fmt.Sprintf("alternates(): HTTP method is NOT POST(%v)", r.Method)
+		return
+	}
+
+	dec := json.NewDecoder(r.Body)
+	if err := dec.Decode(&req); err != nil {
+		errMsg = fmt.Sprintf("alternates(): decode JSON error: %v", err)
+		return
+	}
+
+	alternates = lott.Alternates(req.PrizeNo)
+}
+
+// promoteAlternate revokes a winner and promotes the first available alternate to the same position.
+func promoteAlternate(w http.ResponseWriter, r *http.Request) {
+	type Request struct {
+		PrizeNo       int                 `json:"prize_no"`
+		RevokedWinner lottery.Participant `json:"revoked_winner"`
+		Reason        string              `json:"reason"`
+	}
+
+	type Response struct {
+		Success       bool                `json:"success"`
+		ErrMsg        string              `json:"err_msg,omitempty"`
+		PrizeNo       int                 `json:"prize_no"`
+		RevokedWinner lottery.Participant `json:"revoked_winner"`
+		Alternate     lottery.Winner      `json:"alternate"`
+	}
+
+	var (
+		errMsg    string
+		req       Request
+		alternate lottery.Winner
+	)
+
+	defer func() {
+		resp := Response{}
+
+		if errMsg == "" {
+			resp.Success = true
+		} else {
+			resp.Success = false
+			resp.ErrMsg = errMsg
+			log.Printf("promoteAlternate(): error: %v", errMsg)
+		}
+
+		resp.PrizeNo = req.PrizeNo
+		resp.RevokedWinner = req.RevokedWinner
+		resp.Alternate = alternate
+
+		w.Header().Set("Content-Type", "application/json")
+
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "    ")
+		if err := enc.Encode(&resp); err != nil {
+			log.Printf("promoteAlternate() encode JSON error: %v", err)
+			return
+		}
+	}()
+
+	if r.Method != "POST" {
+		errMsg = fmt.Sprintf("promoteAlternate(): HTTP method is NOT POST(%v)", r.Method)
+		return
+	}
+
+	dec := json.NewDecoder(r.Body)
+	if err := dec.Decode(&req); err != nil {
+		errMsg = fmt.Sprintf("promoteAlternate(): decode JSON error: %v", err)
+		return
+	}
+
+	alternate, err := actor(r, "").PromoteAlternate(req.PrizeNo, req.RevokedWinner, req.Reason)
+	if err != nil {
+		errMsg = fmt.Sprintf("promoteAlternate(): PromoteAlternate() error: %v", err)
+		return
+	}
+
+	if err := lott.SaveToStore(); err != nil {
+		errMsg = fmt.Sprintf("promoteAlternate(): SaveToStore() error: %v", err)
+		return
+	}
+}
+
+// updateClaim updates the claim status of a winner with the given function.
+func updateClaim(w http.ResponseWriter, r *http.Request, funcName string, update func(l *lottery.Lottery, prizeNo int, ID string) error) {
+	type Request struct {
+		PrizeNo int    `json:"prize_no"`
+		ID      string `json:"id"`
+		Reason  string `json:"reason"`
+	}
+
+	type Response struct {
+		Success     bool   `json:"success"`
+		ErrMsg      string `json:"err_msg,omitempty"`
+		PrizeNo     int    `json:"prize_no"`
+		ID          string `json:"id"`
+		ClaimStatus string `json:"claim_status"`
+	}
+
+	var (
+		errMsg string
+		req    Request
+	)
+
+	defer func() {
+		resp := Response{}
+
+		if errMsg == "" {
+			resp.Success = true
+		} else {
+			resp.Success = false
+			resp.ErrMsg = errMsg
+			log.Printf("%v(): error: %v", funcName, errMsg)
+		}
+
+		resp.PrizeNo = req.PrizeNo
+		resp.ID = req.ID
+		resp.ClaimStatus = lott.ClaimStatus(req.PrizeNo, req.ID)
+
+		w.Header().Set("Content-Type", "application/json")
+
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "    ")
+		if err := enc.Encode(&resp); err != nil {
+			log.Printf("%v() encode JSON error: %v", funcName, err)
+			return
+		}
+	}()
+
+	if r.Method != "POST" {
+		errMsg = fmt.Sprintf("%v(): HTTP method is NOT POST(%v)", funcName, r.Method)
+		return
+	}
+
+	dec := json.NewDecoder(r.Body)
+	if err := dec.Decode(&req); err != nil {
+		errMsg = fmt.Sprintf("%v(): decode JSON error: %v", funcName, err)
+		return
+	}
+
+	if err := update(actor(r, req.Reason), req.PrizeNo, req.ID); err != nil {
+		errMsg = fmt.Sprintf("%v(): update claim status error: %v", funcName, err)
+		return
+	}
+
+	if err := lott.SaveToStore(); err != nil {
+		errMsg = fmt.Sprintf("%v(): SaveToStore() error: %v", funcName, err)
+		return
+	}
+}
+
+// undoOrRedo undoes or redoes the last draw-related operation
+// and returns the winners of the prize after it.
+func undoOrRedo(w http.ResponseWriter, r *http.Request, funcName string, f func(l *lottery.Lottery) (lottery.UndoStep, error)) {
+	type Request struct {
+		Reason string `json:"reason"`
+	}
+
+	type Response struct {
+		Success bool             `json:"success"`
+		ErrMsg  string           `json:"err_msg,omitempty"`
+		Step    lottery.UndoStep `json:"step"`
+		Winners []lottery.Winner `json:"winners"`
+	}
+
+	var (
+		errMsg  string
+		req     Request
+		step    lottery.UndoStep
+		winners []lottery.Winner
+	)
+
+	defer func() {
+		resp := Response{}
+
+		if errMsg == "" {
+			resp.Success = true
+		} else {
+			resp.Success = false
+			resp.ErrMsg = errMsg
+			log.Printf("%v(): error: %v", funcName, errMsg)
+		}
+
+		resp.Step = step
+		resp.Winners = winners
+
+		w.Header().Set("Content-Type", "application/json")
+
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "    ")
+		if err := enc.Encode(&resp); err != nil {
+			log.Printf("%v() encode JSON error: %v", funcName, err)
+			return
+		}
+	}()
+
+	if r.Method != "POST" {
+		errMsg = fmt.Sprintf("%v(): HTTP method is NOT POST(%v)", funcName, r.Method)
+		return
+	}
+
+	// The request body is optional.
+	dec := json.NewDecoder(r.Body)
+	if err := dec.Decode(&req); err != nil && err != io.EOF {
+		errMsg = fmt.Sprintf("%v(): decode JSON error: %v", funcName, err)
+		return
+	}
+
+	step, err := f(actor(r, req.Reason))
+	if err != nil {
+		errMsg = fmt.Sprintf("%v(): error: %v", funcName, err)
+		return
+	}
+
+	log.Printf("%v(): %v of prize %v from %v", funcName, step.Type, step.PrizeNo, r.RemoteAddr)
+	winners = lott.Winners(step.PrizeNo)
+
+	if err := lott.SaveToStore(); err != nil {
+		errMsg = fmt.Sprintf("%v(): SaveToStore() error: %v", funcName, err)
+		return
+	}
+}
+
+// undo undoes the last draw, revoke, redraw or clear winners operation.
+func undo(w http.ResponseWriter, r *http.Request) {
+	undoOrRedo(w, r, "undo", (*lottery.Lottery).Undo)
+}
+
+// redo redoes the last undone operation.
+func redo(w http.ResponseWriter, r *http.Request) {
+	undoOrRedo(w, r, "redo", (*lottery.Lottery).Redo)
+}
+
+// finalize finalizes the lottery. Operations can not be undone after it.
+func finalize(w http.ResponseWriter, r *http.Request) {
+	type Request struct {
+		Reason string `json:"reason"`
+	}
+
+	type Response struct {
+		Success bool   `json:"success"`
+		ErrMsg  string `json:"err_msg,omitempty"`
+	}
+
+	var (
+		errMsg string
+		req    Request
+	)
+
+	defer func() {
+		resp := Response{}
+
+		if errMsg == "" {
+			resp.Success = true
+		} else {
+			resp.Success = false
+			resp.ErrMsg = errMsg
+			log.Printf("finalize(): error: %v", errMsg)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "    ")
+		if err := enc.Encode(&resp); err != nil {
+			log.Printf("finalize() encode JSON error: %v", err)
+			return
+		}
+	}()
+
+	if r.Method != "POST" {
+		errMsg = fmt.Sprintf("finalize(): HTTP method is NOT POST(%v)", r.Method)
+		return
+	}
+
+	// The request body is optional.
+	dec := json.NewDecoder(r.Body)
+	if err := dec.Decode(&req); err != nil && err != io.EOF {
+		errMsg = fmt.Sprintf("finalize(): decode JSON error: %v", err)
+		return
+	}
+
+	if err := actor(r, req.Reason).Finalize(); err != nil {
+		errMsg = fmt.Sprintf("finalize(): Finalize() error: %v", err)
+		return
+	}
+
+	if err := lott.SaveToStore(); err != nil {
+		errMsg = fmt.Sprintf("finalize(): SaveToStore() error: %v", err)
+		return
+	}
+}
+
+// confirm confirms a winner has claimed the prize.
+func confirm(w http.ResponseWriter, r *http.Request) {
+	updateClaim(w, r, "confirm", (*lottery.Lottery).Confirm)
+}
+
+// forfeit forfeits the prize of a pending winner.
+func forfeit(w http.ResponseWriter, r *http.Request) {
+	updateClaim(w, r, "forfeit", (*lottery.Lottery).Forfeit)
+}
+
+// decline declines the prize of a winner.
+func decline(w http.ResponseWriter, r *http.Request) {
+	updateClaim(w, r, "decline", (*lottery.Lottery).Decline)
+}
+
+// exportWinners exports the claimed winners as CSV.
+func exportWinners(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		errMsg := fmt.Sprintf("exportWinners(): HTTP method is NOT GET(%v)", r.Method)
+		log.Printf("exportWinners(): error: %v", errMsg)
+		http.Error(w, errMsg, http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", "attachment; filename=winners.csv")
+
+	if err := lott.ExportWinnersCSV(w); err != nil {
+		log.Printf("exportWinners(): ExportWinnersCSV() error: %v", err)
+		return
+	}
+}
+
+// backups returns the backups of the lottery data.
+func backups(w http.ResponseWriter, r *http.Request) {
+	type Response struct {
+		Success bool             `json:"success"`
+		ErrMsg  string           `json:"err_msg,omitempty"`
+		Backups []lottery.Backup `json:"backups"`
+	}
+
+	var (
+		errMsg  string
+		backups []lottery.Backup
+	)
+
+	defer func() {
+		resp := Response{}
+
+		if errMsg == "" {
+			resp.Success = true
+		} else {
+			resp.Success = false
+			resp.ErrMsg = errMsg
+			log.Printf("backups(): error: %v", errMsg)
+		}
+
+		resp.Backups = backups
+
+		w.Header().Set("Content-Type", "application/json")
+
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "    ")
+		if err := enc.Encode(&resp); err != nil {
+			log.Printf("backups() encode JSON error: %v", err)
+			return
+		}
+	}()
+
+	if r.Method != "GET" {
+		errMsg = fmt.Sprintf("backups(): HTTP method is NOT GET(%v)", r.Method)
+		return
+	}
+
+	backups, err := lott.Backups()
+	if err != nil {
+		errMsg = fmt.Sprintf("backups(): Backups() error: %v", err)
+		return
+	}
+}
+
+// restoreBackup verifies and restores a backup of the lottery data.
+func restoreBackup(w http.ResponseWriter, r *http.Request) {
+	type Request struct {
+		File string `json:"file"`
+	}
+
+	type Response struct {
+		Success bool   `json:"success"`
+		ErrMsg  string `json:"err_msg,omitempty"`
+		File    string `json:"file"`
+	}
+
+	var (
+		errMsg string
+		req    Request
+	)
+
+	defer func() {
+		resp := Response{}
+
+		if errMsg == "" {
+			resp.Success = true
+		} else {
+			resp.Success = false
+			resp.ErrMsg = errMsg
+			log.Printf("restoreBackup(): error: %v", errMsg)
+		}
+
+		resp.File = req.File
+
+		w.Header().Set("Content-Type", "application/json")
+
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "    ")
+		if err := enc.Encode(&resp); err != nil {
+			log.Printf("restoreBackup() encode JSON error: %v", err)
+			return
+		}
+	}()
+
+	if r.Method != "POST" {
+		errMsg = fmt.Sprintf("restoreBackup(): HTTP method is NOT POST(%v)", r.Method)
+		return
+	}
+
+	dec := json.NewDecoder(r.Body)
+	if err := dec.Decode(&req); err != nil {
+		errMsg = fmt.Sprintf("restoreBackup(): decode JSON error: %v", err)
+		return
+	}
+
+	if err := actor(r, "").RestoreBackup(req.File); err != nil {
+		errMsg = fmt.Sprintf("restoreBackup(): RestoreBackup() error: %v", err)
+		return
+	}
+
+	log.Printf("restoreBackup(): backup %v restored by %v", req.File, r.RemoteAddr)
+}
+
+// lotteries returns the saved lotteries in the data dir.
+func lotteries(w http.ResponseWriter, r *http.Request) {
+	type Response struct {
+		Success   bool                   `json:"success"`
+		ErrMsg    string                 `json:"err_msg,omitempty"`
+		Lotteries []lottery.CatalogEntry `json:"lotteries"`
+	}
+
+	var (
+		errMsg  string
+		entries []lottery.CatalogEntry
+	)
+
+	defer func() {
+		resp := Response{}
+
+		if errMsg == "" {
+			resp.Success = true
+		} else {
+			resp.Success = false
+			resp.ErrMsg = errMsg
+			log.Printf("lotteries(): error: %v", errMsg)
+		}
+
+		resp.Lotteries = entries
+
+		w.Header().Set("Content-Type", "application/json")
+
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "    ")
+		if err := enc.Encode(&resp); err != nil {
+			log.Printf("lotteries() encode JSON error: %v", err)
+			return
+		}
+	}()
+
+	if r.Method != "GET" {
+		errMsg = fmt.Sprintf("lotteries(): HTTP method is NOT GET(%v)", r.Method)
+		return
+	}
+
+	if !jsonStore {
+		errMsg = "lotteries(): saved lotteries can only be managed with the json store"
+		return
+	}
+
+	entries, err := workspace.Catalog()
+	if err != nil {
+		errMsg = fmt.Sprintf("lotteries(): Catalog() error: %v", err)
+		return
+	}
+}
+
+// openLottery opens a saved lottery by slug and returns its prizes and winners.
+// Query parameter: slug.
+func openLottery(w http.ResponseWriter, r *http.Request) {
+	type Response struct {
+		Success bool                     `json:"success"`
+		ErrMsg  string                   `json:"err_msg,omitempty"`
+		Slug    string                   `json:"slug"`
+		Name    string                   `json:"name"`
+		Prizes  []lottery.Prize          `json:"prizes"`
+		Winners map[int][]lottery.Winner `json:"winners"`
+	}
+
+	var (
+		errMsg string
+		slug   string
+		entry  lottery.CatalogEntry
+		l      *lottery.Lottery
+	)
+
+	defer func() {
+		resp := Response{}
+
+		if errMsg == "" {
+			resp.Success = true
+		} else {
+			resp.Success = false
+			resp.ErrMsg = errMsg
+			log.Printf("openLottery(): error: %v", errMsg)
+		}
+
+		resp.Slug = slug
+		resp.Name = entry.Name
+		if l != nil {
+			resp.Prizes = l.Prizes(false)
+			resp.Winners = l.AllWinners()
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "    ")
+		if err := enc.Encode(&resp); err != nil {
+			log.Printf("openLottery() encode JSON error: %v", err)
+			return
+		}
+	}()
+
+	if r.Method != "GET" {
+		errMsg = fmt.Sprintf("openLottery(): HTTP method is NOT GET(%v)", r.Method)
+		return
+	}
+
+	if !jsonStore {
+		errMsg = "openLottery(): saved lotteries can only be managed with the json store"
+		return
+	}
+
+	slug = r.URL.Query().Get("slug")
+
+	entry, err := workspace.Find(slug)
+	if err != nil {
+		errMsg = fmt.Sprintf("openLottery(): Find() error: %v", err)
+		return
+	}
+
+	l, err = workspace.Open(slug, signerOptions...)
+	if err != nil {
+		errMsg = fmt.Sprintf("openLottery(): Open() error: %v", err)
+		return
+	}
+}
+
+// updateCatalog runs the catalog operation on a saved lottery by slug.
+// The lottery of the server can not be updated.
+func updateCatalog(w http.ResponseWriter, r *http.Request, funcName string, f func(slug string, name string) error) {
+	type Request struct {
+		Slug string `json:"slug"`
+		// Name is the new name of rename.
+		Name string `json:"name,omitempty"`
+	}
+
+	type Response struct {
+		Success bool   `json:"success"`
+		ErrMsg  string `json:"err_msg,omitempty"`
+		Slug    string `json:"slug"`
+	}
+
+	var (
+		errMsg string
+		req    Request
+	)
+
+	defer func() {
+		resp := Response{}
+
+		if errMsg == "" {
+			resp.Success = true
+		} else {
+			resp.Success = false
+			resp.ErrMsg = errMsg
+			log.Printf("%v(): error: %v", funcName, errMsg)
+		}
+
+		resp.Slug = req.Slug
+
+		w.Header().Set("Content-Type", "application/json")
+
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "    ")
+		if err := enc.Encode(&resp); err != nil {
+			log.Printf("%v() encode JSON error: %v", funcName, err)
+			return
+		}
+	}()
+
+	if r.Method != "POST" {
+		errMsg = fmt.Sprintf("%v(): HTTP method is NOT POST(%v)", funcName, r.Method)
+		return
+	}
+
+	if !jsonStore {
+		errMsg = fmt.Sprintf("%v(): saved lotteries can only be managed with the json store", funcName)
+		return
+	}
+
+	dec := json.NewDecoder(r.Body)
+	if err := dec.Decode(&req); err != nil {
+		errMsg = fmt.Sprintf("%v(): decode JSON error: %v", funcName, err)
+		return
+	}
+
+	entry, err := workspace.Find(req.Slug)
+	if err != nil {
+		errMsg = fmt.Sprintf("%v(): Find() error: %v", funcName, err)
+		return
+	}
+
+	if entry.Name == lotteryName {
+		errMsg = fmt.Sprintf("%v(): lottery %v is in use by the server", funcName, entry.Name)
+		return
+	}
+
+	if err := f(req.Slug, req.Name); err != nil {
+		errMsg = fmt.Sprintf("%v(): %v", funcName, err)
+		return
+	}
+
+	log.Printf("%v(): lottery %v updated by %v", funcName, entry.Name, r.RemoteAddr)
+}
+
+// renameLottery renames a saved lottery.
+func renameLottery(w http.ResponseWriter, r *http.Request) {
+	updateCatalog(w, r, "renameLottery", func(slug string, name string) error {
+		return workspace.Rename(slug, name, signerOptions...)
+	})
+}
+
+// archiveLottery moves a saved lottery to the archive.
+func archiveLottery(w http.ResponseWriter, r *http.Request) {
+	updateCatalog(w, r, "archiveLottery", func(slug string, name string) error {
+		return workspace.Archive(slug)
+	})
+}
+
+// unarchiveLottery moves an archived lottery back.
+func unarchiveLottery(w http.ResponseWriter, r *http.Request) {
+	updateCatalog(w, r, "unarchiveLottery", func(slug string, name string) error {
+		return workspace.Unarchive(slug)
+	})
+}
+
+// deleteLottery deletes a saved lottery with its backups and journal.
+func deleteLottery(w http.ResponseWriter, r *http.Request) {
+	updateCatalog(w, r, "deleteLottery", func(slug string, name string) error {
+		return workspace.Delete(slug)
+	})
+}
+
+// audit returns the audit log as JSON or CSV.
+// Query parameters(optional):
+// format: "json"(default) or "csv".
+// actor, operation, prize_no: filter the entries.
+// since, until: time range of the entries in RFC 3339 format.
+func audit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		errMsg := fmt.Sprintf("audit(): HTTP method is NOT GET(%v)", r.Method)
+		log.Printf("audit(): error: %v", errMsg)
+		http.Error(w, errMsg, http.StatusMethodNotAllowed)
+		return
+	}
+
+	values := r.URL.Query()
+	q := lottery.AuditQuery{
+		Actor:     values.Get("actor"),
+		Operation: values.Get("operation"),
+	}
+
+	if s := values.Get("prize_no"); s != "" {
+		prizeNo, err := strconv.Atoi(s)
+		if err != nil {
+			errMsg := fmt.Sprintf("audit(): incorrect prize_no: %v", s)
+			log.Printf("audit(): error: %v", errMsg)
+			http.Error(w, errMsg, http.StatusBadRequest)
+			return
+		}
+		q.PrizeNo = prizeNo
+	}
+
+	for name, tm := range map[string]*time.Time{"since": &q.Since, "until": &q.Until} {
+		s := values.Get(name)
+		if s == "" {
+			continue
+		}
+
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			errMsg := fmt.Sprintf("audit(): incorrect %v: %v", name, s)
+			log.Printf("audit(): error: %v", errMsg)
+			http.Error(w, errMsg, http.StatusBadRequest)
+			return
+		}
+		*tm = t
+	}
+
+	switch values.Get("format") {
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", "attachment; filename=audit.csv")
+
+		if err := lott.ExportAuditCSV(w, q); err != nil {
+			log.Printf("audit(): ExportAuditCSV() error: %v", err)
+			return
+		}
+	default:
+		w.Header().Set("Content-Type", "application/json")
+
+		if err := lott.ExportAuditJSON(w, q); err != nil {
+			log.Printf("audit(): ExportAuditJSON() error: %v", err)
+			return
+		}
+	}
+}
+
+// actor returns the lottery which records the audit metadata of the request.
+// The actor is the authenticated user and the client is the remote address.
+func actor(r *http.Request, reason string) *lottery.Lottery {
+	m := lottery.Meta{Client: r.RemoteAddr, Reason: reason}
+
+	// The user is authenticated by basicAuth only if users are set.
+	if len(users) > 0 {
+		m.Actor, _, _ = r.BasicAuth()
+	}
+
+	return lott.As(m)
+}
+
+// basicAuth requires the requests to be authenticated by the users.
+func basicAuth(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, ok := r.BasicAuth()
+		if ok {
+			expected, found := users[user]
+			ok = found && subtle.ConstantTimeCompare([]byte(password), []byte(expected)) == 1
+		}
+
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Basic realm="lottery"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		h.ServeHTTP(w, r)
+	})
+}
+
+// claimTimeout is called after a pending winner is forfeited by the claim timeout.
+func claimTimeout(prizeNo int, forfeited lottery.Winner, replacement lottery.Winner, err error) {
+	if err != nil {
+		log.Printf("claimTimeout(): prize %v: %v forfeited, replace error: %v", prizeNo, forfeited, err)
+	} else {
+		log.Printf("claimTimeout(): prize %v: %v forfeited, replaced by %v", prizeNo, forfeited, replacement)
+	}
+
+	if err := lott.SaveToStore(); err != nil {
+		log.Printf("claimTimeout(): SaveToStore() error: %v", err)
+		return
+	}
+}
+
+// commit generates a secret seed for the next draw of a prize and returns its commitment.
+func commit(w http.ResponseWriter, r *http.Request) {
+	type Request struct {
+		PrizeNo int `json:"prize_no"`
+	}
+
+	type Response struct {
+		Success    bool   `json:"success"`
+		ErrMsg     string `json:"err_msg,omitempty"`
+		PrizeNo    int    `json:"prize_no"`
+		Commitment string `json:"commitment"`
+	}
+
+	var (
+		errMsg     string
+		req        Request
+		commitment string
+	)
+
+	defer func() {
+		resp := Response{}
+
+		if errMsg == "" {
+			resp.Success = true
+		} else {
+			resp.Success = false
+			resp.ErrMsg = errMsg
+			log.Printf("commit(): error: %v", errMsg)
+		}
+
+		resp.PrizeNo = req.PrizeNo
+		resp.Commitment = commitment
+
+		w.Header().Set("Content-Type", "application/json")
+
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "    ")
+		if err := enc.Encode(&resp); err != nil {
+			log.Printf("commit() encode JSON error: %v", err)
+			return
+		}
+	}()
+
+	if r.Method != "POST" {
+		errMsg = fmt.Sprintf("commit(): HTTP method is NOT POST(%v)", r.Method)
+		return
+	}
+
+	dec := json.NewDecoder(r.Body)
+	if err := dec.Decode(&req); err != nil {
+		errMsg = fmt.Sprintf("commit(): decode JSON error: %v", err)
+		return
+	}
+
+	c, err := lott.Commit(req.PrizeNo)
+	if err != nil {
+		errMsg = fmt.Sprintf("commit(): Commit() error: %v", err)
+		return
+	}
+	commitment = c
+}
+
+// drawRecords returns the commit-reveal draw records of a prize.
+func drawRecords(w http.ResponseWriter, r *http.Request) {
+	type Request struct {
+		PrizeNo int `json:"prize_no"`
+	}
+
+	type Response struct {
+		Success     bool                 `json:"success"`
+		ErrMsg      string               `json:"err_msg,omitempty"`
+		PrizeNo     int                  `json:"prize_no"`
+		DrawRecords []lottery.DrawRecord `json:"draw_records"`
+	}
+
+	var (
+		errMsg      string
+		req         Request
+		drawRecords []lottery.DrawRecord
+	)
+
+	defer func() {
+		resp := Response{}
+
+		if errMsg == "" {
+			resp.Success = true
+		} else {
+			resp.Success = false
+			resp.ErrMsg = errMsg
+			log.Printf("drawRecords(): error: %v", errMsg)
+		}
+
+		resp.PrizeNo = req.PrizeNo
+		resp.DrawRecords = drawRecords
+
+		w.Header().Set("Content-Type", "application/json")
+
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "    ")
+		if err := enc.Encode(&resp); err != nil {
+			log.Printf("drawRecords() encode JSON error: %v", err)
+			return
+		}
+	}()
+
+	if r.Method != "POST" {
+		errMsg = fmt.Sprintf("drawRecords(): HTTP method is NOT POST(%v)", r.Method)
+		return
+	}
+
+	dec := json.NewDecoder(r.Body)
+	if err := dec.Decode(&req); err != nil {
+		errMsg = fmt.Sprintf("drawRecords(): decode JSON error: %v", err)
+		return
+	}
+
+	drawRecords = lott.DrawRecords(req.PrizeNo)
+}
+
+// verify re-runs a commit-reveal draw from its record and checks the winners.
+func verify(w http.ResponseWriter, r *http.Request) {
+	type Response struct {
+		Success  bool   `json:"success"`
+		ErrMsg   string `json:"err_msg,omitempty"`
+		Verified bool   `json:"verified"`
+	}
+
+	var (
+		errMsg   string
+		record   lottery.DrawRecord
+		verified bool
+	)
+
+	defer func() {
+		resp := Response{}
+
+		if errMsg == "" {
+			resp.Success = true
+		} else {
+			resp.Success = false
+			resp.ErrMsg = errMsg
+			log.Printf("verify(): error: %v", errMsg)
+		}
+
+		resp.Verified = verified
+
+		w.Header().Set("Content-Type", "application/json")
+
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "    ")
+		if err := enc.Encode(&resp); err != nil {
+			log.Printf("verify() encode JSON error: %v", err)
+			return
+		}
+	}()
+
+	if r.Method != "POST" {
+		errMsg = fmt.Sprintf("verify(): HTTP method is NOT POST(%v)", r.Method)
+		return
+	}
+
+	dec := json.NewDecoder(r.Body)
+	if err := dec.Decode(&record); err != nil {
+		errMsg = fmt.Sprintf("verify(): decode JSON error: %v", err)
+		return
+	}
+
+	if err := lottery.Verify(record); err != nil {
+		errMsg = fmt.Sprintf("verify(): Verify() error: %v", err)
+		return
+	}
+	verified = true
+}
+
+// GetCurrentExecDir gets the current executable path.
+func GetCurrentExecDir() (dir string, err error) {
+	p, err := exec.LookPath(os.Args[0])
+	if err != nil {
+		return "", err
+	}
+
+	absPath, err := filepath.Abs(p)
+	if err != nil {
+		return "", err
+	}
+
+	dir = filepath.Dir(absPath)
+	return dir, nil
+}
+
+// openStore opens the store in the workspace to save the lottery data set by
+// the config.
+func openStore(ws *lottery.Workspace, config Config) (lottery.Store, error) {
+	switch config.Store {
+	case "", "json":
+		s := ws.Store()
+		if config.Backups != 0 {
+			s.SetBackups(config.Backups)
+		}
+		return s, nil
+	case "sqlite":
+		file := config.SQLiteFile
+		if file == "" {
+			file = path.Join(ws.Dir(), "lottery.db")
+		}
+		return sqlitestore.Open(file)
+	default:
+		return nil, fmt.Errorf("incorrect store: %v", config.Store)
+	}
+}
+
+// readKeyFile reads the hex encoded key file.
+func readKeyFile(file string) ([]byte, error) {
+	buf, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return hex.DecodeString(strings.TrimSpace(string(buf)))
+}
+
+// newSigner returns the signer of the saved data set by the config.
+func newSigner(config SigningConfig) (lottery.Signer, error) {
+	switch config.Alg {
+	case lottery.SignHMACSHA256:
+		key, err := readKeyFile(config.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		return lottery.NewHMACSigner(key)
+	case lottery.SignEd25519:
+		if config.KeyFile == "" {
+			key, err := readKeyFile(config.PublicKeyFile)
+			if err != nil {
+				return nil, err
+			}
+			return lottery.NewEd25519Verifier(ed25519.PublicKey(key))
+		}
+
+		key, err := readKeyFile(config.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		if len(key) == ed25519.SeedSize {
+			key = ed25519.NewKeyFromSeed(key)
+		}
+		return lottery.NewEd25519Signer(ed25519.PrivateKey(key))
+	default:
+		return nil, fmt.Errorf("incorrect signing alg: %v", config.Alg)
+	}
+}
+
+// logImportReport logs the errors and warnings of the imported CSV.
+func logImportReport(name string, report lottery.ImportReport) {
+	for _, issue := range report.Errors {
+		log.Printf("%v error: %v", name, issue)
+	}
+
+	for _, issue := range report.Warnings {
+		log.Printf("%v warning: %v", name, issue)
+	}
+
+	if len(report.Errors) > 0 {
+		log.Printf("%v: %v of %v rows imported", name, report.Imported, report.Rows)
+	}
+}
+
+func loadConfig() (Config, error) {
+	config := Config{}
+
+	f := path.Join(serverRoot, "settings/config.json")
+
+	buf, err := ioutil.ReadFile(f)
+	if err != nil {
+		return config, err
+	}
+
+	if err := json.Unmarshal(buf, &config); err != nil {
+		return config, err
+	}
+	return config, nil
+}
+
+func init() {
+	// Get absolute path of server root(current executable).
+	serverRoot, _ = GetCurrentExecDir()
+	// Get static folder path.
+	staticFolderPath = path.Join(serverRoot, "./statics/dist/spa")
+	log.Printf("staticFolderPath: %v", staticFolderPath)
+
+	participantsCSV = path.Join(serverRoot, "./settings/participants.csv")
+	prizesCSV = path.Join(serverRoot, "./settings/prizes.csv")
+	blacklistsJSON = path.Join(serverRoot, "./settings/blacklists.json")
+	rulesJSON = path.Join(serverRoot, "./settings/rules.json")
+}
+
+func main() {
+	// Load config.
+	config, err := loadConfig()
+	if err != nil {
+		log.Printf("loadConfig() error: %v", err)
+		return
+	}
+
+	// Do not log the passwords.
+	users = config.Users
+	config.Users = nil
+	log.Printf("load config successfully. config: %v", config)
+
+	// Create the workspace for the lottery data.
+	dataDir := config.DataDir
+	if dataDir == "" {
+		dataDir = lottery.AppDataDir
+	}
+	workspace = lottery.NewWorkspace(dataDir)
+	if err := workspace.Init(); err != nil {
+		log.Printf("create data dir error: %v", err)
+		return
+	}
+
+	// Open the store to save the lottery data.
+	store, err := openStore(workspace, config)
+	if err != nil {
+		log.Printf("open store error: %v", err)
+		return
+	}
+
+	// Saved lotteries are managed in the workspace with the json store only.
+	_, jsonStore = store.(*lottery.FileStore)
+
+	options := []lottery.Option{lottery.WithStore(store)}
+
+	// Sign the saved data(optional).
+	if config.Signing != nil {
+		signer, err := newSigner(*config.Signing)
+		if err != nil {
+			log.Printf("create signer error: %v", err)
+			return
+		}
+		signerOptions = append(signerOptions, lottery.WithSigner(signer))
+		options = append(options, signerOptions...)
+	}
+
+	// Create a lottery.
+	lotteryName = config.LotteryName
+	lott = workspace.New(lotteryName, options...)
+
+	stored, err := lott.Stored()
+	if err != nil {
+		log.Printf("check stored data error: %v", err)
+		return
+	}
+
+	// Check if the journal or data file is already saved.
+	journal := lott.JournalFile()
+	_, err = os.Stat(journal)
+	journalFound := err == nil
+
+	if journalFound {
+		// The lottery started and recorded the operations.
+		// Rebuild the state by replaying the journal and continue.
+		log.Printf("journal file found")
+		if err := lott.OpenJournal(journal); err != nil {
+			log.Printf("open journal error: %v", err)
+			return
+		}
+	} else if stored {
+		// The lottery started and saved the data.
+		// Load the data and continue.
+		log.Printf("saved data found")
+		if err := lott.LoadFromStore(); err != nil {
+			log.Printf("load saved data error: %v", err)
+			return
+		}
+	} else {
+		// 1st run for the lottery.
+		// Load participants.
+		report, err := lott.ImportParticipantsCSVFile(participantsCSV, config.ParticipantsImport)
+		logImportReport("participants CSV", report)
+		if err != nil {
+			log.Printf("load participants CSV error: %v", err)
+			return
+		}
+		log.Printf("load participants CSV successfully")
+		log.Printf("participants: %v", lott.Participants())
+
+		// Load prizes.
+		report, err = lott.ImportPrizesCSVFile(prizesCSV, config.PrizesImport)
+		logImportReport("prizes CSV", report)
+		if err != nil {
+			log.Printf("load prizes CSV error: %v", err)
+			return
+		}
+		log.Printf("load prizes CSV successfully")
+		log.Printf("prizes: %v", lott.Prizes(true))
+
+		// Load blacklists.
+		if err := lott.LoadBlacklistsJSONFile(blacklistsJSON); err != nil {
+			log.Printf("load blacklists JSON error: %v", err)
+			return
+		}
+		log.Printf("load blacklists JSON successfully")
+		log.Printf("blacklists: %v", lott.Blacklists())
+
+		// Set group cap(optional).
+		if config.GroupCap != nil {
+			if err := lott.SetGroupCap(config.GroupCap.Attribute, config.GroupCap.Max); err != nil {
+				log.Printf("set group cap error: %v", err)
+				return
+			}
+		}
+
+		// Load eligibility rules of prizes(optional).
+		if _, err := os.Stat(rulesJSON); err == nil {
+			if err := lott.LoadPrizeRulesJSONFile(rulesJSON); err != nil {
+				log.Printf("load rules JSON error: %v", err)
+				return
+			}
+			log.Printf("load rules JSON successfully")
+		}
+	}
+
+	// Record the following operations in the journal.
+	// The current state is recorded as a snapshot in the new journal.
+	if !journalFound {
+		if err := lott.OpenJournal(journal); err != nil {
+			log.Printf("open journal error: %v", err)
+			return
+		}
+	}
+
+	// Set claim timeout(optional).
+	if config.ClaimTimeout > 0 {
+		lott.SetClaimTimeout(time.Duration(config.ClaimTimeout)*time.Second, claimTimeout)
+	}
+
+	// Set revoke policies(optional).
+	for reason, policy := range config.RevokePolicies {
+		if err := lott.SetRevokePolicy(reason, policy); err != nil {
+			log.Printf("set revoke policy of reason %v error: %v", reason, err)
 			return
 		}
-		log.Printf("load blacklists JSON successfully")
-		log.Printf("blacklists: %v", lott.Blacklists())
 	}
 
 	// Serve Static Files.
@@ -495,13 +1989,80 @@ func main() {
 	// Draw a prize.
 	http.HandleFunc("/draw", draw)
 
+	// Draw a prize allocated across the strata of an attribute.
+	http.HandleFunc("/draw_stratified", drawStratified)
+
+	// Draw a batch of the remaining places of a prize.
+	http.HandleFunc("/draw_batch", drawBatch)
+
 	// Revoke winners.
 	http.HandleFunc("/revoke", revoke)
 
 	// Redraw a prize.
 	http.HandleFunc("/redraw", redraw)
 
-	err = http.ListenAndServe(config.Addr, nil)
+	// Revoke winners and draw the replacements in one operation.
+	http.HandleFunc("/replace", replace)
+
+	// Get alternates of a prize.
+	http.HandleFunc("/alternates", alternates)
+
+	// Promote an alternate to replace a winner.
+	http.HandleFunc("/promote_alternate", promoteAlternate)
+
+	// Confirm a winner has claimed the prize.
+	http.HandleFunc("/confirm", confirm)
+
+	// Forfeit the prize of a pending winner.
+	http.HandleFunc("/forfeit", forfeit)
+
+	// Decline the prize of a winner.
+	http.HandleFunc("/decline", decline)
+
+	// Export claimed winners as CSV.
+	http.HandleFunc("/export_winners", exportWinners)
+
+	// Undo or redo the last draw-related operation.
+	http.HandleFunc("/undo", undo)
+	http.HandleFunc("/redo", redo)
+
+	// Finalize the lottery.
+	http.HandleFunc("/finalize", finalize)
+
+	// Compact the journal.
+	http.HandleFunc("/compact_journal", compactJournal)
+
+	// Get or restore the backups of the lottery data.
+	http.HandleFunc("/backups", backups)
+	http.HandleFunc("/restore_backup", restoreBackup)
+
+	// Get or export the audit log.
+	http.HandleFunc("/audit", audit)
+
+	// Manage saved lotteries in the data dir.
+	http.HandleFunc("/admin/lotteries", lotteries)
+	http.HandleFunc("/admin/lottery", openLottery)
+	http.HandleFunc("/admin/rename", renameLottery)
+	http.HandleFunc("/admin/archive", archiveLottery)
+	http.HandleFunc("/admin/unarchive", unarchiveLottery)
+	http.HandleFunc("/admin/delete", deleteLottery)
+
+	// Commit a secret seed before drawing a prize.
+	http.HandleFunc("/commit", commit)
+
+	// Get commit-reveal draw records.
+	http.HandleFunc("/draw_records", drawRecords)
+
+	// Verify a commit-reveal draw record.
+	http.HandleFunc("/verify", verify)
+
+	// Authenticate the requests(optional).
+	var handler http.Handler = http.DefaultServeMux
+	if len(users) > 0 {
+		handler = basicAuth(handler)
+	}
+
+	err = http.ListenAndServe(config.Addr, handler)
 	if err != nil {
 		log.Fatal("ListenAndServe: ", err)
 	}
@@ -0,0 +1,101 @@
+// Command upgrade upgrades the saved data files of old versions to the current
+// save format version in place. The original files are kept as backups named
+// "<file>.v<version>.bak".
+//
+// Usage:
+//
+//	upgrade [-dir <data dir>] [-alg hmac-sha256|ed25519 -key <key file>] [file ...]
+//
+// All data files in the data dir are upgraded if no files are given.
+// If the signing alg is set, the files must be signed with the key. Signatures
+// of the files are verified before upgrade and the upgraded data is re-signed
+// with the key.
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"github.com/northbright/lottery-go/lottery"
+)
+
+var (
+	dir     = flag.String("dir", lottery.AppDataDir, "data dir of the lotteries")
+	alg     = flag.String("alg", "", "signing alg of the data files: hmac-sha256 or ed25519(optional)")
+	keyFile = flag.String("key", "", "hex encoded signing key file(HMAC key or Ed25519 seed / private key)")
+)
+
+func readKeyFile(file string) ([]byte, error) {
+	buf, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return hex.DecodeString(strings.TrimSpace(string(buf)))
+}
+
+// newSigner returns the signer set by the flags.
+func newSigner() (lottery.Signer, error) {
+	if *alg == "" {
+		return nil, nil
+	}
+
+	key, err := readKeyFile(*keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	switch *alg {
+	case lottery.SignHMACSHA256:
+		return lottery.NewHMACSigner(key)
+	case lottery.SignEd25519:
+		if len(key) == ed25519.SeedSize {
+			key = ed25519.NewKeyFromSeed(key)
+		}
+		return lottery.NewEd25519Signer(ed25519.PrivateKey(key))
+	default:
+		return nil, fmt.Errorf("incorrect signing alg: %v", *alg)
+	}
+}
+
+func main() {
+	flag.Parse()
+
+	signer, err := newSigner()
+	if err != nil {
+		log.Fatalf("newSigner() error: %v", err)
+	}
+
+	files := flag.Args()
+	if len(files) == 0 {
+		if files, err = filepath.Glob(filepath.Join(*dir, "*.json")); err != nil {
+			log.Fatalf("Glob() error: %v", err)
+		}
+	}
+
+	failed := false
+	for _, file := range files {
+		version, err := lottery.UpgradeSaveFile(file, signer)
+		if err != nil {
+			log.Printf("upgrade %v error: %v", file, err)
+			failed = true
+			continue
+		}
+
+		if version == lottery.SaveVersion {
+			log.Printf("%v: already version %v", file, version)
+		} else {
+			log.Printf("%v: upgraded from version %v to %v", file, version, lottery.SaveVersion)
+		}
+	}
+
+	if failed {
+		log.Fatalf("failed to upgrade some files")
+	}
+}
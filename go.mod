@@ -1,8 +1,5 @@
 module github.com/northbright/lottery-go
 
-go 1.15
+go 1.17
 
-require (
-	github.com/northbright/csvhelper v0.0.0-20180402060143-eaaadd19d5ca
-	github.com/northbright/pathhelper v0.0.0-20170328083152-212a42014bc5
-)
+require golang.org/x/text v0.13.0
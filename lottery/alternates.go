@@ -0,0 +1,85 @@
+package lottery
+
+// SetPrizeAlternates sets the amount of alternates(standby winners) drawn
+// alongside the winners of the prize by Draw.
+func (l *Lottery) SetPrizeAlternates(prizeNo int, amount int) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	prize, ok := l.prizes[prizeNo]
+	if !ok {
+		return ErrPrizeNo
+	}
+
+	if amount < 0 {
+		return ErrAlternates
+	}
+
+	prize.Alternates = amount
+	if err := l.record(Event{Type: EventSetPrizeAlternates, Prize: &prize, Meta: l.meta}); err != nil {
+		return err
+	}
+
+	l.prizes[prizeNo] = prize
+	return nil
+}
+
+// Alternates returns the remaining alternates of the prize in order.
+func (l *Lottery) Alternates(prizeNo int) []Participant {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if _, ok := l.alternates[prizeNo]; !ok {
+		return []Participant{}
+	}
+
+	return l.alternates[prizeNo]
+}
+
+// PromoteAlternate revokes the winner of the prize with the reason code and
+// promotes the first available alternate to the same position without
+// drawing again. The policy of the reason applies to the revoked winner as
+// Revoke does.
+// Alternates which are no longer available(e.g. won another prize)
+// are skipped and removed.
+// It returns the promoted alternate. If no alternate is available,
+// it returns ErrNoAvailableAlternates and nothing is changed.
+func (l *Lottery) PromoteAlternate(prizeNo int, revokedWinner Participant, reason string) (Winner, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if _, ok := l.prizes[prizeNo]; !ok {
+		return Winner{}, ErrPrizeNo
+	}
+
+	index := l.winnerIndex(prizeNo, revokedWinner.ID)
+	if index < 0 {
+		return Winner{}, ErrRevokedWinnerNotMatch
+	}
+
+	available := participantSliceToMap(l.availableParticipants(prizeNo))
+	r := l.rollbackPoint(prizeNo)
+
+	alternates := l.alternates[prizeNo]
+	for i, alternate := range alternates {
+		if _, ok := available[alternate.ID]; !ok {
+			continue
+		}
+
+		revoked := l.winners[prizeNo][index]
+		l.revokeWinner(prizeNo, revoked, reason)
+
+		winners := l.winners[prizeNo]
+		l.winners[prizeNo] = append(winners[:index:index], winners[index+1:]...)
+
+		l.alternates[prizeNo] = alternates[i+1:]
+		winner := l.insertWinner(prizeNo, index, alternate, revoked)
+		if err := l.recordPrize(Event{Type: EventPromoteAlternate, PrizeNo: prizeNo, IDs: []string{revokedWinner.ID}, Reason: reason, Meta: l.meta}, r); err != nil {
+			return Winner{}, err
+		}
+
+		return winner, nil
+	}
+
+	return Winner{}, ErrNoAvailableAlternates
+}
@@ -0,0 +1,116 @@
+package lottery_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/northbright/lottery-go/lottery"
+)
+
+func TestPromoteAlternate(t *testing.T) {
+	l := newSeededLottery(t, "alternates")
+
+	if err := l.SetPrizeAlternates(3, 2); err != nil {
+		t.Fatalf("SetPrizeAlternates() error: %v", err)
+	}
+
+	winners, err := l.Draw(3)
+	if err != nil {
+		t.Fatalf("Draw() error: %v", err)
+	}
+
+	alternates := l.Alternates(3)
+	if len(winners) != 5 || len(alternates) != 2 {
+		t.Fatalf("got %v winners and %v alternates, want 5 and 2", len(winners), len(alternates))
+	}
+
+	for _, a := range alternates {
+		for _, w := range winners {
+			if a.ID == w.ID {
+				t.Errorf("alternate %v is also a winner", a)
+			}
+		}
+	}
+
+	revoked := winners[2]
+	first := alternates[0]
+	promoted, err := l.PromoteAlternate(3, revoked.Participant, lottery.ReasonAbsent)
+	if err != nil {
+		t.Fatalf("PromoteAlternate() error: %v", err)
+	}
+	if promoted.ID != first.ID {
+		t.Errorf("promoted %v, want the first alternate %v", promoted, first)
+	}
+	if w := l.Winners(3); w[2].ID != first.ID || len(w) != 5 {
+		t.Errorf("winners after promotion: %v, want %v at position 2", w, first)
+	}
+	if n := len(l.Alternates(3)); n != 1 {
+		t.Errorf("got %v alternates after promotion, want 1", n)
+	}
+
+	// The revoke policy of the reason applies to the revoked winner.
+	revocations := l.Revocations(3)
+	if len(revocations) != 1 || revocations[0].ID != revoked.ID || revocations[0].Policy != lottery.RevokeExcludeLottery {
+		t.Errorf("revocations = %v, want %v excluded from the lottery", revocations, revoked.ID)
+	}
+	for _, p := range l.AvailableParticipants(3) {
+		if p.ID == revoked.ID {
+			t.Errorf("revoked winner %v is available again", revoked.ID)
+		}
+	}
+
+	// Alternates are saved.
+	buf := &bytes.Buffer{}
+	if err := l.Save(buf); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+
+	loaded := newSeededLottery(t, "alternates")
+	if err := loaded.Load(buf); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if n := len(loaded.Alternates(3)); n != 1 {
+		t.Errorf("got %v alternates after loading, want 1", n)
+	}
+}
+
+func TestPromoteAlternateNotAvailable(t *testing.T) {
+	l := newSeededLottery(t, "alternates")
+
+	if err := l.SetPrizeAlternates(3, 1); err != nil {
+		t.Fatalf("SetPrizeAlternates() error: %v", err)
+	}
+
+	winners, err := l.Draw(3)
+	if err != nil {
+		t.Fatalf("Draw() error: %v", err)
+	}
+
+	// The only alternate wins another prize.
+	alternate := l.Alternates(3)[0]
+	others := []string{}
+	for _, p := range l.Participants() {
+		if p.ID != alternate.ID {
+			others = append(others, p.ID)
+		}
+	}
+	l.SetBlacklist(2, others)
+	if _, err := l.Draw(1); err != nil {
+		t.Fatalf("Draw() error: %v", err)
+	}
+
+	if _, err := l.PromoteAlternate(3, winners[0].Participant, lottery.ReasonAbsent); err != lottery.ErrNoAvailableAlternates {
+		t.Fatalf("PromoteAlternate() = %v, want %v", err, lottery.ErrNoAvailableAlternates)
+	}
+
+	// Nothing is changed.
+	if n := len(l.Alternates(3)); n != 1 {
+		t.Errorf("got %v alternates, want 1", n)
+	}
+	if w := l.Winners(3); len(w) != len(winners) || w[0].ID != winners[0].ID {
+		t.Errorf("winners = %v, want %v", w, winners)
+	}
+	if n := len(l.Revocations(3)); n != 0 {
+		t.Errorf("got %v revocations, want 0", n)
+	}
+}
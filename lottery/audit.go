@@ -0,0 +1,183 @@
+package lottery
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Meta is the actor and reason metadata of a mutating operation.
+// Use Lottery.As to run operations with the metadata.
+type Meta struct {
+	// Actor is the operator(e.g. user name) who runs the operation.
+	Actor string `json:"actor,omitempty"`
+	// Client is the client(e.g. remote address) where the operation comes from.
+	Client string `json:"client,omitempty"`
+	// Reason is why the operation is run.
+	Reason string `json:"reason,omitempty"`
+}
+
+// AuditEntry is an entry of the audit log.
+// Operations done by the lottery itself(e.g. claim timeout) have no actor.
+type AuditEntry struct {
+	Seq  int    `json:"seq"`
+	Time string `json:"time"`
+	// Operation is the event type of the operation.
+	Operation string   `json:"operation"`
+	PrizeNo   int      `json:"prize_no,omitempty"`
+	IDs       []string `json:"ids,omitempty"`
+	Actor     string   `json:"actor,omitempty"`
+	Client    string   `json:"client,omitempty"`
+	// Reason is the reason of the operation.
+	// It's the reason code of the event(e.g. revoke reason) if no reason is given.
+	Reason string `json:"reason,omitempty"`
+}
+
+// AuditQuery filters the entries of the audit log.
+// Zero fields match all entries.
+type AuditQuery struct {
+	Actor     string
+	Operation string
+	PrizeNo   int
+	// Since and Until are the time range of the entries(inclusive).
+	Since time.Time
+	Until time.Time
+}
+
+// As returns the lottery which records the metadata in the audit log and
+// the journal with the operations. It shares the state with l.
+// e.g. l.As(Meta{Actor: "alice"}).Draw(1)
+func (l *Lottery) As(meta Meta) *Lottery {
+	return &Lottery{l.state, &meta}
+}
+
+// appendAudit appends the entry of the event to the audit log.
+func (l *Lottery) appendAudit(e Event) {
+	entry := AuditEntry{
+		Seq:       len(l.audit) + 1,
+		Time:      e.Time,
+		Operation: e.Type,
+		PrizeNo:   e.PrizeNo,
+		IDs:       e.IDs,
+		Reason:    e.Reason,
+	}
+
+	// Events of the prize settings carry the prize only.
+	if e.Prize != nil {
+		entry.PrizeNo = e.Prize.No
+	}
+
+	if e.Meta != nil {
+		entry.Actor = e.Meta.Actor
+		entry.Client = e.Meta.Client
+		if e.Meta.Reason != "" {
+			entry.Reason = e.Meta.Reason
+		}
+	}
+
+	l.audit = append(l.audit, entry)
+}
+
+// match reports whether the entry matches the query.
+func (q AuditQuery) match(entry AuditEntry) bool {
+	if q.Actor != "" && q.Actor != entry.Actor {
+		return false
+	}
+
+	if q.Operation != "" && q.Operation != entry.Operation {
+		return false
+	}
+
+	if q.PrizeNo != 0 && q.PrizeNo != entry.PrizeNo {
+		return false
+	}
+
+	if q.Since.IsZero() && q.Until.IsZero() {
+		return true
+	}
+
+	tm, err := time.ParseInLocation("2006-01-02 15:04:05", entry.Time, time.Local)
+	if err != nil {
+		return false
+	}
+
+	if !q.Since.IsZero() && tm.Before(q.Since.Truncate(time.Second)) {
+		return false
+	}
+
+	if !q.Until.IsZero() && tm.After(q.Until) {
+		return false
+	}
+
+	return true
+}
+
+// AuditLog returns the entries of the audit log which match the query in order.
+func (l *Lottery) AuditLog(q AuditQuery) []AuditEntry {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return l.auditLog(q)
+}
+
+func (l *Lottery) auditLog(q AuditQuery) []AuditEntry {
+	entries := []AuditEntry{}
+
+	for _, entry := range l.audit {
+		if q.match(entry) {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries
+}
+
+// ExportAuditCSV exports the entries of the audit log which match the query
+// as CSV. IDs are separated by spaces.
+func (l *Lottery) ExportAuditCSV(w io.Writer, q AuditQuery) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"Seq", "Time", "Operation", "Prize No", "IDs", "Actor", "Client", "Reason"}); err != nil {
+		return err
+	}
+
+	for _, entry := range l.auditLog(q) {
+		prizeNo := ""
+		if entry.PrizeNo != 0 {
+			prizeNo = strconv.Itoa(entry.PrizeNo)
+		}
+
+		row := []string{
+			strconv.Itoa(entry.Seq),
+			entry.Time,
+			entry.Operation,
+			prizeNo,
+			strings.Join(entry.IDs, " "),
+			entry.Actor,
+			entry.Client,
+			entry.Reason,
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// ExportAuditJSON exports the entries of the audit log which match the query
+// as a JSON array.
+func (l *Lottery) ExportAuditJSON(w io.Writer, q AuditQuery) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "    ")
+	return enc.Encode(l.auditLog(q))
+}
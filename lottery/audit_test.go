@@ -0,0 +1,180 @@
+package lottery_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/northbright/lottery-go/lottery"
+)
+
+func TestAuditLog(t *testing.T) {
+	l := newSeededLottery(t, "audit")
+	alice := lottery.Meta{Actor: "alice", Client: "10.0.0.1:5000", Reason: "annual party"}
+	bob := lottery.Meta{Actor: "bob", Client: "10.0.0.2:5000"}
+
+	winners, err := l.As(alice).Draw(3)
+	if err != nil {
+		t.Fatalf("Draw() error: %v", err)
+	}
+	if err := l.As(bob).Revoke(3, []lottery.Participant{winners[0].Participant}, lottery.ReasonAbsent); err != nil {
+		t.Fatalf("Revoke() error: %v", err)
+	}
+	if _, err := l.Redraw(3, 1); err != nil {
+		t.Fatalf("Redraw() error: %v", err)
+	}
+
+	entries := l.AuditLog(lottery.AuditQuery{PrizeNo: 3})
+	if len(entries) != 3 {
+		t.Fatalf("got %v entries of prize 3, want 3", len(entries))
+	}
+
+	draw := entries[0]
+	if draw.Operation != lottery.EventDraw || draw.Actor != "alice" || draw.Client != "10.0.0.1:5000" || draw.Reason != "annual party" {
+		t.Errorf("draw entry = %+v", draw)
+	}
+
+	// The revoke reason is recorded if no reason is given.
+	revoke := entries[1]
+	if revoke.Actor != "bob" || revoke.Reason != lottery.ReasonAbsent || !reflect.DeepEqual(revoke.IDs, []string{winners[0].ID}) {
+		t.Errorf("revoke entry = %+v", revoke)
+	}
+
+	if entries[2].Actor != "" {
+		t.Errorf("redraw entry actor = %v, want empty", entries[2].Actor)
+	}
+
+	if got := l.AuditLog(lottery.AuditQuery{Actor: "bob"}); len(got) != 1 || got[0].Operation != lottery.EventRevoke {
+		t.Errorf("AuditLog(bob) = %v, want the revoke", got)
+	}
+
+	// Prize settings are logged with the prize no.
+	if err := l.As(alice).SetPrizeAlternates(3, 1); err != nil {
+		t.Fatalf("SetPrizeAlternates() error: %v", err)
+	}
+	if got := l.AuditLog(lottery.AuditQuery{PrizeNo: 3, Operation: lottery.EventSetPrizeAlternates}); len(got) != 1 {
+		t.Errorf("AuditLog(set_prize_alternates of prize 3) = %v, want 1 entry", got)
+	}
+
+	if got := l.AuditLog(lottery.AuditQuery{Until: time.Now().Add(-time.Hour)}); len(got) != 0 {
+		t.Errorf("AuditLog(an hour ago) = %v, want no entries", got)
+	}
+
+	if got := l.AuditLog(lottery.AuditQuery{Since: time.Now().Add(-time.Minute)}); len(got) != len(l.AuditLog(lottery.AuditQuery{})) {
+		t.Errorf("AuditLog(a minute ago) = %v, want all entries", got)
+	}
+}
+
+func TestExportAudit(t *testing.T) {
+	l := newSeededLottery(t, "audit")
+	if _, err := l.As(lottery.Meta{Actor: "alice", Reason: "draw, 2nd prize"}).Draw(2); err != nil {
+		t.Fatalf("Draw() error: %v", err)
+	}
+	q := lottery.AuditQuery{Operation: lottery.EventDraw}
+
+	buf := &bytes.Buffer{}
+	if err := l.ExportAuditCSV(buf, q); err != nil {
+		t.Fatalf("ExportAuditCSV() error: %v", err)
+	}
+	rows, err := csv.NewReader(buf).ReadAll()
+	if err != nil {
+		t.Fatalf("read CSV error: %v", err)
+	}
+	if len(rows) != 2 || rows[1][2] != lottery.EventDraw || rows[1][3] != "2" || rows[1][5] != "alice" || rows[1][7] != "draw, 2nd prize" {
+		t.Errorf("exported CSV = %v", rows)
+	}
+
+	buf.Reset()
+	if err := l.ExportAuditJSON(buf, q); err != nil {
+		t.Fatalf("ExportAuditJSON() error: %v", err)
+	}
+	entries := []lottery.AuditEntry{}
+	if err := json.Unmarshal(buf.Bytes(), &entries); err != nil {
+		t.Fatalf("Unmarshal() error: %v", err)
+	}
+	if !reflect.DeepEqual(entries, l.AuditLog(q)) {
+		t.Errorf("exported JSON = %v, want %v", entries, l.AuditLog(q))
+	}
+}
+
+func TestAuditLogReplay(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "lottery.journal")
+
+	l := openJournal(t, file)
+	if err := l.As(lottery.Meta{Actor: "admin"}).LoadParticipantsCSVFile("settings/participants.example.csv"); err != nil {
+		t.Fatalf("LoadParticipantsCSVFile() error: %v", err)
+	}
+	if err := l.LoadPrizesCSVFile("settings/prizes.example.csv"); err != nil {
+		t.Fatalf("LoadPrizesCSVFile() error: %v", err)
+	}
+	if _, err := l.As(lottery.Meta{Actor: "alice"}).Draw(5); err != nil {
+		t.Fatalf("Draw() error: %v", err)
+	}
+	if err := l.CompactJournal(); err != nil {
+		t.Fatalf("CompactJournal() error: %v", err)
+	}
+	if _, err := l.As(lottery.Meta{Actor: "bob"}).Draw(4); err != nil {
+		t.Fatalf("Draw() error: %v", err)
+	}
+	buf := &bytes.Buffer{}
+	if err := l.Save(buf); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+	if err := l.As(lottery.Meta{Actor: "admin"}).Load(buf); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	l.CloseJournal()
+
+	replayed := openJournal(t, file)
+	defer replayed.CloseJournal()
+
+	want := l.AuditLog(lottery.AuditQuery{})
+	if got := replayed.AuditLog(lottery.AuditQuery{}); !reflect.DeepEqual(got, want) {
+		t.Errorf("replayed audit log = %v, want %v", got, want)
+	}
+}
+
+func TestAuditLogRestoreBackup(t *testing.T) {
+	ws := lottery.NewWorkspace(t.TempDir())
+	l := saveLottery(t, ws, "audit")
+
+	if _, err := l.As(lottery.Meta{Actor: "mallory"}).Draw(2); err != nil {
+		t.Fatalf("Draw() error: %v", err)
+	}
+	if err := l.SaveToStore(); err != nil {
+		t.Fatalf("SaveToStore() error: %v", err)
+	}
+	before := l.AuditLog(lottery.AuditQuery{})
+
+	backups, err := l.Backups()
+	if err != nil || len(backups) != 1 {
+		t.Fatalf("Backups() = %v, %v, want 1 backup", backups, err)
+	}
+	if err := l.As(lottery.Meta{Actor: "admin"}).RestoreBackup(backups[0].File); err != nil {
+		t.Fatalf("RestoreBackup() error: %v", err)
+	}
+
+	// Entries after the backup are kept and the restore is recorded.
+	after := l.AuditLog(lottery.AuditQuery{})
+	if len(after) != len(before)+1 || !reflect.DeepEqual(after[:len(before)], before) {
+		t.Fatalf("audit log after restore = %v, want %v and the restore", after, before)
+	}
+	if e := after[len(after)-1]; e.Operation != lottery.EventRestoreBackup || e.Actor != "admin" {
+		t.Errorf("restore entry = %+v", e)
+	}
+	if got := l.AuditLog(lottery.AuditQuery{Actor: "mallory"}); len(got) != 1 {
+		t.Errorf("AuditLog(mallory) = %v, want the draw", got)
+	}
+
+	// Loading the saved data is recorded too.
+	if err := l.As(lottery.Meta{Actor: "admin"}).LoadFromStore(); err != nil {
+		t.Fatalf("LoadFromStore() error: %v", err)
+	}
+	if got := l.AuditLog(lottery.AuditQuery{Operation: lottery.EventLoad}); len(got) != 1 || got[0].Actor != "admin" {
+		t.Errorf("AuditLog(load) = %v, want the load by admin", got)
+	}
+}
@@ -0,0 +1,451 @@
+package lottery
+
+import (
+	"crypto/md5"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// CatalogEntry is a saved lottery in the workspace.
+type CatalogEntry struct {
+	Name string `json:"name"`
+	// Slug is the human-readable ID of the lottery. See Slug.
+	Slug string `json:"slug"`
+	// File is the data file name.
+	File        string `json:"file"`
+	LastUpdated string `json:"last_updated"`
+	Prizes      int    `json:"prizes"`
+	// Places is the total amount of the prizes.
+	Places int `json:"places"`
+	// Winners is the amount of drawn winners.
+	Winners   int  `json:"winners"`
+	Finalized bool `json:"finalized"`
+	Archived  bool `json:"archived"`
+	// Error is the error of reading the data file.
+	// Only File and Archived are set if it's not empty.
+	Error string `json:"error,omitempty"`
+}
+
+const (
+	// ArchiveDir is the sub dir of archived lotteries in the workspace.
+	ArchiveDir = "archive"
+)
+
+var (
+	ErrLotteryNotFound = fmt.Errorf("lottery not found")
+	ErrLotteryExists   = fmt.Errorf("lottery already exists")
+	ErrLotteryName     = fmt.Errorf("incorrect lottery name")
+	ErrSlugConflict    = fmt.Errorf("slug matches more than one lottery")
+	ErrArchived        = fmt.Errorf("lottery is archived")
+	ErrNotArchived     = fmt.Errorf("lottery is not archived")
+)
+
+// Slug returns the human-readable ID of the lottery name.
+// Letters are lowercased and other characters except digits are replaced
+// with "-", e.g. "New Year Party 2024" -> "new-year-party-2024".
+// It returns the first 8 hex digits of the MD5 of the name if the name has
+// no letters or digits.
+func Slug(name string) string {
+	b := strings.Builder{}
+	dash := false
+
+	for _, r := range strings.ToLower(name) {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			dash = true
+			continue
+		}
+
+		if dash && b.Len() > 0 {
+			b.WriteByte('-')
+		}
+		dash = false
+		b.WriteRune(r)
+	}
+
+	if b.Len() == 0 {
+		return fmt.Sprintf("%x", md5.Sum([]byte(name)))[:8]
+	}
+	return b.String()
+}
+
+// Catalog returns the saved lotteries in the workspace sorted by name.
+// The archived ones follow the others.
+func (w *Workspace) Catalog() ([]CatalogEntry, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	return w.catalog()
+}
+
+func (w *Workspace) catalog() ([]CatalogEntry, error) {
+	entries, err := w.entries(false)
+	if err != nil {
+		return nil, err
+	}
+
+	archived, err := w.archived.entries(true)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(entries, archived...), nil
+}
+
+// entries returns the saved lotteries in the dir of the workspace.
+func (w *Workspace) entries(archived bool) ([]CatalogEntry, error) {
+	files, err := filepath.Glob(filepath.Join(w.dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []CatalogEntry{}
+	for _, f := range files {
+		e := CatalogEntry{File: filepath.Base(f), Archived: archived}
+
+		data, err := loadDataFile(f)
+		if err != nil {
+			e.Error = err.Error()
+			entries = append(entries, e)
+			continue
+		}
+
+		e.Name = data.Name
+		e.Slug = Slug(data.Name)
+		e.LastUpdated = data.LastUpdated
+		e.Prizes = len(data.Prizes)
+		e.Finalized = data.Finalized
+
+		for _, prize := range data.Prizes {
+			e.Places += prize.Amount
+		}
+
+		for _, winners := range data.Winners {
+			e.Winners += len(winners)
+		}
+
+		entries = append(entries, e)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Name != entries[j].Name {
+			return entries[i].Name < entries[j].Name
+		}
+		return entries[i].File < entries[j].File
+	})
+
+	return entries, nil
+}
+
+// find returns the saved lottery of the slug.
+func (w *Workspace) find(slug string) (CatalogEntry, error) {
+	entries, err := w.catalog()
+	if err != nil {
+		return CatalogEntry{}, err
+	}
+
+	found := []CatalogEntry{}
+	for _, e := range entries {
+		if e.Error == "" && e.Slug == slug {
+			found = append(found, e)
+		}
+	}
+
+	switch len(found) {
+	case 0:
+		return CatalogEntry{}, ErrLotteryNotFound
+	case 1:
+		return found[0], nil
+	default:
+		return CatalogEntry{}, ErrSlugConflict
+	}
+}
+
+// Find returns the saved lottery of the slug.
+func (w *Workspace) Find(slug string) (CatalogEntry, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	return w.find(slug)
+}
+
+// workspaceOf returns the workspace of the data file of the saved lottery.
+func (w *Workspace) workspaceOf(e CatalogEntry) *Workspace {
+	if e.Archived {
+		return w.archived
+	}
+	return w
+}
+
+// Open creates the lottery of the slug and loads its saved data.
+// Archived lotteries are opened in the archive.
+func (w *Workspace) Open(slug string, options ...Option) (*Lottery, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	e, err := w.find(slug)
+	if err != nil {
+		return nil, err
+	}
+
+	ws := w.workspaceOf(e)
+	l := ws.New(e.Name, options...)
+	if err := ws.load(l); err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// load loads the data of the lottery from the JSON file store of the
+// workspace, even if the lottery has another store.
+func (w *Workspace) load(l *Lottery) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return l.loadFromStore(w.store, EventLoad)
+}
+
+// Rename renames the saved lottery of the slug.
+// The data file, the backups and the journal are moved to the new name.
+// If it fails, the saved lottery is not changed.
+// Options(e.g. WithSigner) are used to load and re-sign the data.
+// It returns ErrNoSigningKey if the data is signed and no signer is given.
+// The lottery should not be opened while it's renamed.
+func (w *Workspace) Rename(slug string, name string, options ...Option) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if strings.TrimSpace(name) == "" {
+		return ErrLotteryName
+	}
+
+	e, err := w.find(slug)
+	if err != nil {
+		return err
+	}
+
+	if name == e.Name {
+		return nil
+	}
+
+	entries, err := w.catalog()
+	if err != nil {
+		return err
+	}
+
+	for _, other := range entries {
+		if other.Name == name || (other.Slug == Slug(name) && other.Name != e.Name) {
+			return ErrLotteryExists
+		}
+	}
+
+	ws := w.workspaceOf(e)
+	l := ws.New(e.Name, options...)
+	if err := ws.load(l); err != nil {
+		return err
+	}
+
+	return ws.rename(l, name)
+}
+
+// rename renames the saved lottery in the workspace.
+// The data is saved with the new name, then the journal and the backups are
+// moved to the new name and the data file of the old name is removed.
+// Backups are moved as is, because their names are not used to find them.
+// If it fails before the data file of the old name is removed, the data file
+// of the new name is removed and the moved files are moved back, so the
+// lottery is either renamed or not changed.
+// Signed data can not be renamed without a signer to sign it again.
+func (w *Workspace) rename(l *Lottery, name string) (err error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	oldName := l.name
+
+	saved, err := w.store.Load(oldName)
+	if err != nil {
+		return err
+	}
+
+	if saved.Signature != nil && l.signer == nil {
+		return ErrNoSigningKey
+	}
+
+	l.name = name
+
+	// created is the data file of the new name and moved are the moved files
+	// in [old file, new file] pairs to roll back.
+	created := ""
+	moved := [][2]string{}
+	defer func() {
+		if err == nil {
+			return
+		}
+
+		l.name = oldName
+		if created != "" {
+			os.Remove(created)
+		}
+		for i := len(moved) - 1; i >= 0; i-- {
+			os.Rename(moved[i][1], moved[i][0])
+		}
+	}()
+
+	move := func(file, newFile string) error {
+		if err := os.Rename(file, newFile); err != nil {
+			return err
+		}
+		moved = append(moved, [2]string{file, newFile})
+		return nil
+	}
+
+	data, err := l.saveData()
+	if err != nil {
+		return err
+	}
+
+	backups, err := w.store.Backups(oldName)
+	if err != nil {
+		return err
+	}
+
+	file := w.store.File(name)
+	err = writeFileAtomic(file, func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "    ")
+		return enc.Encode(&data)
+	})
+	if err != nil {
+		return err
+	}
+	created = file
+
+	if err := move(w.JournalFile(oldName), w.JournalFile(name)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	oldPrefix := filepath.Base(w.store.File(oldName))
+	prefix := filepath.Base(file)
+
+	for _, b := range backups {
+		newFile := prefix + strings.TrimPrefix(b.File, oldPrefix)
+		if err := move(filepath.Join(w.dir, b.File), filepath.Join(w.dir, newFile)); err != nil {
+			return err
+		}
+	}
+
+	if err := syncDir(w.dir); err != nil {
+		return err
+	}
+
+	return os.Remove(w.store.File(oldName))
+}
+
+// Archive moves the saved lottery of the slug to the archive.
+// The lottery should not be opened while it's archived.
+func (w *Workspace) Archive(slug string) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	e, err := w.find(slug)
+	if err != nil {
+		return err
+	}
+
+	if e.Archived {
+		return ErrArchived
+	}
+
+	return w.move(e.Name, w.archived)
+}
+
+// Unarchive moves the archived lottery of the slug back to the workspace.
+func (w *Workspace) Unarchive(slug string) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	e, err := w.find(slug)
+	if err != nil {
+		return err
+	}
+
+	if !e.Archived {
+		return ErrNotArchived
+	}
+
+	return w.archived.move(e.Name, w)
+}
+
+// move moves the data file, the backups and the journal of the lottery to
+// another workspace.
+func (w *Workspace) move(name string, to *Workspace) error {
+	exists, err := to.store.Exists(name)
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		return ErrLotteryExists
+	}
+
+	if err := to.Init(); err != nil {
+		return err
+	}
+
+	backups, err := w.store.Backups(name)
+	if err != nil {
+		return err
+	}
+
+	for _, backup := range backups {
+		if err := os.Rename(filepath.Join(w.dir, backup.File), filepath.Join(to.dir, backup.File)); err != nil {
+			return err
+		}
+	}
+
+	if err := renameIfExists(w.JournalFile(name), to.JournalFile(name)); err != nil {
+		return err
+	}
+
+	// Move the data file at last, so the lottery is still in the catalog if
+	// it fails to move other files.
+	if err := os.Rename(w.store.File(name), to.store.File(name)); err != nil {
+		return err
+	}
+
+	return syncDir(to.dir)
+}
+
+// Delete deletes the data file, the backups and the journal of the saved
+// lottery of the slug. The lottery should not be opened while it's deleted.
+func (w *Workspace) Delete(slug string) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	e, err := w.find(slug)
+	if err != nil {
+		return err
+	}
+
+	ws := w.workspaceOf(e)
+	if err := os.Remove(ws.JournalFile(e.Name)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return ws.store.remove(e.Name)
+}
+
+// renameIfExists renames the file if it exists.
+func renameIfExists(file, newFile string) error {
+	err := os.Rename(file, newFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,213 @@
+package lottery_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/northbright/lottery-go/lottery"
+)
+
+func TestSlug(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		slug string
+	}{
+		{"New Year Party 2024", "new-year-party-2024"},
+		{"  Annual -- Lucky Draw! ", "annual-lucky-draw"},
+		{"年会抽奖", "年会抽奖"},
+	} {
+		if slug := lottery.Slug(c.name); slug != c.slug {
+			t.Errorf("Slug(%q) = %q, want %q", c.name, slug, c.slug)
+		}
+	}
+
+	if len(lottery.Slug("!!!")) != 8 {
+		t.Errorf("Slug(%q) = %q, want 8 hex digits", "!!!", lottery.Slug("!!!"))
+	}
+}
+
+// saveLottery saves a lottery with the prizes and a drawn prize in the workspace.
+func saveLottery(t *testing.T, ws *lottery.Workspace, name string, options ...lottery.Option) *lottery.Lottery {
+	l := ws.New(name, options...)
+	if err := l.LoadParticipantsCSVFile("settings/participants.example.csv"); err != nil {
+		t.Fatalf("LoadParticipantsCSVFile() error: %v", err)
+	}
+	if err := l.LoadPrizesCSVFile("settings/prizes.example.csv"); err != nil {
+		t.Fatalf("LoadPrizesCSVFile() error: %v", err)
+	}
+	if _, err := l.Draw(3); err != nil {
+		t.Fatalf("Draw() error: %v", err)
+	}
+	if err := l.SaveToFile(); err != nil {
+		t.Fatalf("SaveToFile() error: %v", err)
+	}
+	return l
+}
+
+func TestCatalog(t *testing.T) {
+	signer, _ := lottery.NewHMACSigner([]byte("secret"))
+	ws := lottery.NewWorkspace(t.TempDir())
+
+	l := saveLottery(t, ws, "New Year Party", lottery.WithSigner(signer))
+	// Keep a backup.
+	if err := l.SaveToFile(); err != nil {
+		t.Fatalf("SaveToFile() error: %v", err)
+	}
+	saveLottery(t, ws, "Spring Festival")
+
+	entries, err := ws.Catalog()
+	if err != nil {
+		t.Fatalf("Catalog() error: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Slug != "new-year-party" || entries[1].Slug != "spring-festival" {
+		t.Fatalf("Catalog() = %v", entries)
+	}
+	e := entries[0]
+	if e.Name != "New Year Party" || e.Prizes != len(l.Prizes(false)) || e.Winners != len(l.Winners(3)) || e.Places == 0 || e.Archived {
+		t.Errorf("entry = %+v", e)
+	}
+
+	// Open by slug.
+	opened, err := ws.Open("new-year-party", lottery.WithSigner(signer))
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	if len(opened.Winners(3)) != len(l.Winners(3)) {
+		t.Errorf("opened winners = %v, want %v", opened.Winners(3), l.Winners(3))
+	}
+	if _, err := ws.Open("missing"); err != lottery.ErrLotteryNotFound {
+		t.Errorf("Open() missing = %v, want %v", err, lottery.ErrLotteryNotFound)
+	}
+
+	// Rename.
+	if err := ws.Rename("new-year-party", "New Year Gala"); err != lottery.ErrNoSigningKey {
+		t.Errorf("Rename() signed data without signer = %v, want %v", err, lottery.ErrNoSigningKey)
+	}
+	if _, err := ws.Find("new-year-party"); err != nil {
+		t.Errorf("Find() after failed rename error: %v", err)
+	}
+	if err := ws.Rename("new-year-party", "Spring Festival", lottery.WithSigner(signer)); err != lottery.ErrLotteryExists {
+		t.Errorf("Rename() to existing = %v, want %v", err, lottery.ErrLotteryExists)
+	}
+	if err := ws.Rename("new-year-party", "New Year Gala", lottery.WithSigner(signer)); err != nil {
+		t.Fatalf("Rename() error: %v", err)
+	}
+	if _, err := ws.Find("new-year-party"); err != lottery.ErrLotteryNotFound {
+		t.Errorf("Find() old slug = %v, want %v", err, lottery.ErrLotteryNotFound)
+	}
+	renamed, err := ws.Open("new-year-gala", lottery.WithSigner(signer))
+	if err != nil {
+		t.Fatalf("Open() renamed error: %v", err)
+	}
+	if len(renamed.Winners(3)) != len(l.Winners(3)) {
+		t.Errorf("renamed winners = %v, want %v", renamed.Winners(3), l.Winners(3))
+	}
+	backups, err := renamed.Backups()
+	if err != nil || len(backups) != 1 {
+		t.Fatalf("Backups() of renamed = %v, %v, want 1 backup", backups, err)
+	}
+	if err := renamed.RestoreBackup(backups[0].File); err != nil {
+		t.Errorf("RestoreBackup() of renamed error: %v", err)
+	}
+	if _, err := ws.Open("new-year-gala", lottery.WithSigner(signer)); err != nil {
+		t.Errorf("Open() restored error: %v", err)
+	}
+	if _, err := os.Stat(ws.Store().File("New Year Party")); !os.IsNotExist(err) {
+		t.Errorf("old data file error: %v, want not exist", err)
+	}
+
+	// Archive.
+	if err := ws.Archive("new-year-gala"); err != nil {
+		t.Fatalf("Archive() error: %v", err)
+	}
+	if err := ws.Archive("new-year-gala"); err != lottery.ErrArchived {
+		t.Errorf("Archive() archived = %v, want %v", err, lottery.ErrArchived)
+	}
+	e, err = ws.Find("new-year-gala")
+	if err != nil || !e.Archived {
+		t.Errorf("Find() archived = %+v, %v", e, err)
+	}
+	if ws.New("New Year Gala").DataFileExists() {
+		t.Errorf("DataFileExists() of archived = true, want false")
+	}
+	if _, err := ws.Open("new-year-gala", lottery.WithSigner(signer)); err != nil {
+		t.Errorf("Open() archived error: %v", err)
+	}
+
+	if err := ws.Unarchive("new-year-gala"); err != nil {
+		t.Fatalf("Unarchive() error: %v", err)
+	}
+	if !ws.New("New Year Gala").DataFileExists() {
+		t.Errorf("DataFileExists() of unarchived = false, want true")
+	}
+
+	// Delete.
+	if err := ws.Delete("spring-festival"); err != nil {
+		t.Fatalf("Delete() error: %v", err)
+	}
+	entries, _ = ws.Catalog()
+	if len(entries) != 1 || entries[0].Name != "New Year Gala" {
+		t.Errorf("Catalog() after delete = %v", entries)
+	}
+}
+
+func TestRenameRollback(t *testing.T) {
+	ws := lottery.NewWorkspace(t.TempDir())
+
+	l := saveLottery(t, ws, "New Year Party")
+	// Keep a backup.
+	if err := l.SaveToFile(); err != nil {
+		t.Fatalf("SaveToFile() error: %v", err)
+	}
+	if err := l.OpenJournal(ws.JournalFile("New Year Party")); err != nil {
+		t.Fatalf("OpenJournal() error: %v", err)
+	}
+	if err := l.CloseJournal(); err != nil {
+		t.Fatalf("CloseJournal() error: %v", err)
+	}
+
+	// The journal can not be moved to the new name.
+	if err := os.MkdirAll(filepath.Join(ws.JournalFile("New Year Gala"), "dir"), 0755); err != nil {
+		t.Fatalf("MkdirAll() error: %v", err)
+	}
+
+	if err := ws.Rename("new-year-party", "New Year Gala"); err == nil {
+		t.Fatalf("Rename() = nil, want error")
+	}
+
+	// Nothing is changed.
+	entries, err := ws.Catalog()
+	if err != nil {
+		t.Fatalf("Catalog() error: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Name != "New Year Party" {
+		t.Errorf("Catalog() = %v, want New Year Party only", entries)
+	}
+	if backups, err := ws.New("New Year Party").Backups(); err != nil || len(backups) != 1 {
+		t.Errorf("Backups() = %v, %v, want 1 backup", backups, err)
+	}
+	if backups, err := ws.New("New Year Gala").Backups(); err != nil || len(backups) != 0 {
+		t.Errorf("Backups() of new name = %v, %v, want none", backups, err)
+	}
+	if _, err := os.Stat(ws.JournalFile("New Year Party")); err != nil {
+		t.Errorf("journal error: %v", err)
+	}
+
+	// Rename after the journal of the new name is removed.
+	if err := os.RemoveAll(ws.JournalFile("New Year Gala")); err != nil {
+		t.Fatalf("RemoveAll() error: %v", err)
+	}
+	if err := ws.Rename("new-year-party", "New Year Gala"); err != nil {
+		t.Fatalf("Rename() error: %v", err)
+	}
+	if tmp, _ := filepath.Glob(filepath.Join(ws.Dir(), "*.tmp")); len(tmp) != 0 {
+		t.Errorf("temporary files are left: %v", tmp)
+	}
+	if backups, err := ws.New("New Year Gala").Backups(); err != nil || len(backups) != 1 {
+		t.Errorf("Backups() of renamed = %v, %v, want 1 backup", backups, err)
+	}
+	if _, err := os.Stat(ws.JournalFile("New Year Gala")); err != nil {
+		t.Errorf("journal of renamed error: %v", err)
+	}
+}
@@ -0,0 +1,356 @@
+package lottery
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+const (
+	// ClaimPending means the winner has not claimed the prize yet.
+	ClaimPending = "pending"
+	// ClaimClaimed means the winner has claimed the prize.
+	ClaimClaimed = "claimed"
+	// ClaimForfeited means the winner has forfeited the prize,
+	// e.g. absent when the claim timeout expires.
+	ClaimForfeited = "forfeited"
+	// ClaimDeclined means the winner has declined the prize.
+	ClaimDeclined = "declined"
+)
+
+var (
+	ErrWinnerNotFound = fmt.Errorf("winner not found")
+	ErrClaimStatus    = fmt.Errorf("incorrect claim status")
+)
+
+// ClaimTimeoutHandler is called after a pending winner is forfeited by the
+// claim timeout. The replacement is the promoted alternate or the new winner
+// drawn at the same position. err is not nil if no replacement is found, or
+// if the forfeiture can not be recorded. In the latter case, the winner is
+// pending again and the claim timer restarts.
+type ClaimTimeoutHandler func(prizeNo int, forfeited Winner, replacement Winner, err error)
+
+// markDrawn sets the pending claim status of the new winners.
+// It starts the claim timers if the claim timeout is set.
+func (l *Lottery) markDrawn(prizeNo int, winners []Winner) {
+	if _, ok := l.claims[prizeNo]; !ok {
+		l.claims[prizeNo] = make(map[string]string)
+	}
+
+	for _, winner := range winners {
+		l.claims[prizeNo][winner.ID] = ClaimPending
+		l.startClaimTimer(prizeNo, winner.ID)
+	}
+}
+
+func (l *Lottery) startClaimTimer(prizeNo int, ID string) {
+	if l.claimTimeout <= 0 {
+		return
+	}
+
+	l.stopClaimTimer(prizeNo, ID)
+
+	if _, ok := l.claimTimers[prizeNo]; !ok {
+		l.claimTimers[prizeNo] = make(map[string]*time.Timer)
+	}
+
+	// The timer func reads t after locking the mutex held by the caller,
+	// so t is always assigned before it's used.
+	var t *time.Timer
+	t = time.AfterFunc(l.claimTimeout, func() {
+		l.claimTimeoutExpired(prizeNo, ID, &t)
+	})
+	l.claimTimers[prizeNo][ID] = t
+}
+
+func (l *Lottery) stopClaimTimer(prizeNo int, ID string) {
+	if t, ok := l.claimTimers[prizeNo][ID]; ok {
+		t.Stop()
+		delete(l.claimTimers[prizeNo], ID)
+	}
+}
+
+func (l *Lottery) stopClaimTimers() {
+	for prizeNo, timers := range l.claimTimers {
+		for ID := range timers {
+			l.stopClaimTimer(prizeNo, ID)
+		}
+	}
+}
+
+// clearClaims removes the claim statuses and timers of the prize.
+func (l *Lottery) clearClaims(prizeNo int) {
+	for ID := range l.claimTimers[prizeNo] {
+		l.stopClaimTimer(prizeNo, ID)
+	}
+	delete(l.claims, prizeNo)
+}
+
+// SetClaimTimeout sets the time for a winner to claim the prize.
+// A pending winner is forfeited automatically after the timeout and
+// replaced by the first available alternate or a new winner drawn at the
+// same position. Then the handler is called if it's not nil.
+// The timeout <= 0 disables the claim timeout.
+// Timers of existing pending winners restart from now.
+func (l *Lottery) SetClaimTimeout(timeout time.Duration, handler ClaimTimeoutHandler) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.stopClaimTimers()
+	l.claimTimeout = timeout
+	l.onClaimTimeout = handler
+
+	l.startClaimTimers()
+}
+
+// startClaimTimers starts the claim timers of all pending winners.
+func (l *Lottery) startClaimTimers() {
+	for prizeNo, winners := range l.winners {
+		for _, winner := range winners {
+			if l.claimStatus(prizeNo, winner.ID) == ClaimPending {
+				l.startClaimTimer(prizeNo, winner.ID)
+			}
+		}
+	}
+}
+
+func (l *Lottery) claimTimeoutExpired(prizeNo int, ID string, t **time.Timer) {
+	l.mutex.Lock()
+
+	// Ignore the timer if it was stopped or replaced after it fired.
+	if l.claimTimers[prizeNo][ID] != *t || l.claimStatus(prizeNo, ID) != ClaimPending {
+		l.mutex.Unlock()
+		return
+	}
+	delete(l.claimTimers[prizeNo], ID)
+
+	r := l.rollbackPoint(prizeNo)
+	forfeited, index, err := l.forfeit(prizeNo, ID, ClaimForfeited)
+	if err != nil {
+		l.mutex.Unlock()
+		return
+	}
+
+	replacement, err := l.replaceAt(prizeNo, index, forfeited)
+	if recordErr := l.recordPrize(Event{Type: EventClaimTimeout, PrizeNo: prizeNo, IDs: []string{ID}}, r); recordErr != nil {
+		// The winner is pending again and the claim timer restarts.
+		replacement, err = Winner{}, recordErr
+	}
+	handler := l.onClaimTimeout
+	l.mutex.Unlock()
+
+	if handler != nil {
+		handler(prizeNo, forfeited, replacement, err)
+	}
+}
+
+// replaceAt replaces the removed winner at the index with the first
+// available alternate. If there's no available alternate,
+// it draws a new winner.
+func (l *Lottery) replaceAt(prizeNo int, index int, removed Winner) (Winner, error) {
+	available := l.availableParticipants(prizeNo)
+	availableMap := participantSliceToMap(available)
+
+	replacement := Participant{}
+	found := false
+	alternates := l.alternates[prizeNo]
+	for i, alternate := range alternates {
+		if _, ok := availableMap[alternate.ID]; ok {
+			replacement = alternate
+			found = true
+			l.alternates[prizeNo] = alternates[i+1:]
+			break
+		}
+	}
+
+	if !found {
+		if len(alternates) > 0 {
+			l.alternates[prizeNo] = []Participant{}
+		}
+
+		if len(available) == 0 {
+			return Winner{}, ErrNoAvailableParticipants
+		}
+
+		drawn, err := l.drawPrize(prizeNo, 1, 0, available)
+		if err != nil {
+			return Winner{}, err
+		}
+		replacement = drawn[0]
+	}
+
+	return l.insertWinner(prizeNo, index, replacement, removed), nil
+}
+
+// forfeit removes the winner of the prize and sets the claim status.
+// It returns the removed winner and its position.
+func (l *Lottery) forfeit(prizeNo int, ID string, status string) (Winner, int, error) {
+	if _, ok := l.prizes[prizeNo]; !ok {
+		return Winner{}, -1, ErrPrizeNo
+	}
+
+	index := l.winnerIndex(prizeNo, ID)
+	if index < 0 {
+		return Winner{}, -1, ErrWinnerNotFound
+	}
+
+	winners := l.winners[prizeNo]
+	winner := winners[index]
+	l.winners[prizeNo] = append(winners[:index:index], winners[index+1:]...)
+	l.updatePositions(prizeNo)
+
+	l.stopClaimTimer(prizeNo, ID)
+	if _, ok := l.claims[prizeNo]; !ok {
+		l.claims[prizeNo] = make(map[string]string)
+	}
+	l.claims[prizeNo][ID] = status
+	return winner, index, nil
+}
+
+// Confirm confirms the winner of the prize has claimed the prize.
+// Only pending winners can be confirmed.
+func (l *Lottery) Confirm(prizeNo int, ID string) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if _, ok := l.prizes[prizeNo]; !ok {
+		return ErrPrizeNo
+	}
+
+	switch l.claimStatus(prizeNo, ID) {
+	case ClaimPending:
+	case "":
+		return ErrWinnerNotFound
+	default:
+		return ErrClaimStatus
+	}
+
+	r := l.rollbackPoint(prizeNo)
+
+	l.stopClaimTimer(prizeNo, ID)
+	if _, ok := l.claims[prizeNo]; !ok {
+		l.claims[prizeNo] = make(map[string]string)
+	}
+	l.claims[prizeNo][ID] = ClaimClaimed
+	return l.recordPrize(Event{Type: EventConfirm, PrizeNo: prizeNo, IDs: []string{ID}, Meta: l.meta}, r)
+}
+
+// Forfeit forfeits the prize of the pending winner. The winner is removed
+// from the winners of the prize and is no longer available for the prize.
+// Use Redraw to draw a replacement.
+func (l *Lottery) Forfeit(prizeNo int, ID string) error {
+	return l.giveUp(prizeNo, ID, ClaimForfeited, EventForfeit)
+}
+
+// Decline declines the prize of the pending or claimed winner.
+// The winner is removed from the winners of the prize and is no longer
+// available for the prize.
+// Use Redraw to draw a replacement.
+func (l *Lottery) Decline(prizeNo int, ID string) error {
+	return l.giveUp(prizeNo, ID, ClaimDeclined, EventDecline)
+}
+
+func (l *Lottery) giveUp(prizeNo int, ID string, status string, eventType string) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	switch l.claimStatus(prizeNo, ID) {
+	case ClaimPending:
+	case ClaimClaimed:
+		if status != ClaimDeclined {
+			return ErrClaimStatus
+		}
+	case "":
+		return ErrWinnerNotFound
+	default:
+		return ErrClaimStatus
+	}
+
+	r := l.rollbackPoint(prizeNo)
+	if _, _, err := l.forfeit(prizeNo, ID, status); err != nil {
+		return err
+	}
+
+	return l.recordPrize(Event{Type: eventType, PrizeNo: prizeNo, IDs: []string{ID}, Meta: l.meta}, r)
+}
+
+// ClaimStatus returns the claim status of the winner of the prize.
+// It returns an empty string if the participant never won the prize.
+func (l *Lottery) ClaimStatus(prizeNo int, ID string) string {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return l.claimStatus(prizeNo, ID)
+}
+
+// claimStatus returns the claim status of the participant for the prize.
+// Winners without a status(e.g. loaded from data saved by old versions)
+// are pending.
+func (l *Lottery) claimStatus(prizeNo int, ID string) string {
+	if status, ok := l.claims[prizeNo][ID]; ok {
+		return status
+	}
+
+	if l.winnerIndex(prizeNo, ID) >= 0 {
+		return ClaimPending
+	}
+
+	return ""
+}
+
+// gaveUp reports whether the participant forfeited or declined the prize.
+func (l *Lottery) gaveUp(prizeNo int, ID string) bool {
+	status := l.claims[prizeNo][ID]
+	return status == ClaimForfeited || status == ClaimDeclined
+}
+
+// ClaimedWinners returns the winners of the prize who have claimed the prize.
+func (l *Lottery) ClaimedWinners(prizeNo int) []Winner {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return l.claimedWinners(prizeNo)
+}
+
+func (l *Lottery) claimedWinners(prizeNo int) []Winner {
+	winners := []Winner{}
+
+	for _, winner := range l.winners[prizeNo] {
+		if l.claimStatus(prizeNo, winner.ID) == ClaimClaimed {
+			winners = append(winners, winner)
+		}
+	}
+
+	return winners
+}
+
+// ExportWinnersCSV exports the final winners as CSV.
+// Only claimed winners are exported.
+func (l *Lottery) ExportWinnersCSV(w io.Writer) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"Prize No", "Prize Name", "ID", "Name"}); err != nil {
+		return err
+	}
+
+	for _, prize := range prizeMapToSlice(l.prizes, false) {
+		for _, winner := range l.claimedWinners(prize.No) {
+			row := []string{
+				strconv.Itoa(prize.No),
+				prize.Name,
+				winner.ID,
+				winner.Name,
+			}
+			if err := writer.Write(row); err != nil {
+				return err
+			}
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,103 @@
+package lottery_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+	"time"
+
+	"github.com/northbright/lottery-go/lottery"
+)
+
+func TestClaimWorkflow(t *testing.T) {
+	l := newSeededLottery(t, "claim")
+
+	winners, err := l.Draw(2)
+	if err != nil {
+		t.Fatalf("Draw() error: %v", err)
+	}
+
+	for _, w := range winners {
+		if s := l.ClaimStatus(2, w.ID); s != lottery.ClaimPending {
+			t.Errorf("ClaimStatus(2, %v) = %v, want %v", w.ID, s, lottery.ClaimPending)
+		}
+	}
+
+	if err := l.Confirm(2, winners[0].ID); err != nil {
+		t.Fatalf("Confirm() error: %v", err)
+	}
+	if err := l.Forfeit(2, winners[0].ID); err != lottery.ErrClaimStatus {
+		t.Errorf("Forfeit() of a claimed winner = %v, want %v", err, lottery.ErrClaimStatus)
+	}
+
+	if err := l.Forfeit(2, winners[1].ID); err != nil {
+		t.Fatalf("Forfeit() error: %v", err)
+	}
+	if s := l.ClaimStatus(2, winners[1].ID); s != lottery.ClaimForfeited {
+		t.Errorf("ClaimStatus() = %v, want %v", s, lottery.ClaimForfeited)
+	}
+	for _, p := range l.AvailableParticipants(2) {
+		if p.ID == winners[1].ID {
+			t.Errorf("forfeited winner %v is available for the prize", p)
+		}
+	}
+
+	// Only claimed winners are exported.
+	buf := &bytes.Buffer{}
+	if err := l.ExportWinnersCSV(buf); err != nil {
+		t.Fatalf("ExportWinnersCSV() error: %v", err)
+	}
+	rows, err := csv.NewReader(buf).ReadAll()
+	if err != nil {
+		t.Fatalf("read exported CSV error: %v", err)
+	}
+	if len(rows) != 2 || rows[1][2] != winners[0].ID {
+		t.Errorf("exported rows: %v, want header and %v", rows, winners[0].ID)
+	}
+}
+
+func TestClaimTimeout(t *testing.T) {
+	l := newSeededLottery(t, "timeout")
+
+	type result struct {
+		forfeited   lottery.Winner
+		replacement lottery.Winner
+		err         error
+	}
+	ch := make(chan result, 1)
+
+	if err := l.SetPrizeAlternates(1, 1); err != nil {
+		t.Fatalf("SetPrizeAlternates() error: %v", err)
+	}
+
+	winners, err := l.Draw(1)
+	if err != nil {
+		t.Fatalf("Draw() error: %v", err)
+	}
+	alternate := l.Alternates(1)[0]
+
+	l.SetClaimTimeout(10*time.Millisecond, func(prizeNo int, forfeited, replacement lottery.Winner, err error) {
+		ch <- result{forfeited, replacement, err}
+	})
+
+	select {
+	case r := <-ch:
+		if r.err != nil {
+			t.Fatalf("replace error: %v", r.err)
+		}
+		if r.forfeited.ID != winners[0].ID || r.replacement.ID != alternate.ID {
+			t.Errorf("forfeited %v and replaced by %v, want %v and %v", r.forfeited, r.replacement, winners[0], alternate)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("claim timeout is not triggered")
+	}
+
+	l.SetClaimTimeout(0, nil)
+
+	if w := l.Winners(1); len(w) != 1 || w[0].ID != alternate.ID {
+		t.Errorf("winners after timeout: %v, want %v", w, alternate)
+	}
+	if s := l.ClaimStatus(1, winners[0].ID); s != lottery.ClaimForfeited {
+		t.Errorf("ClaimStatus() = %v, want %v", s, lottery.ClaimForfeited)
+	}
+}
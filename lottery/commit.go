@@ -0,0 +1,243 @@
+package lottery
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// DrawRecord is the public record of a commit-reveal draw.
+// Anyone can re-run the draw with Verify to check the winners.
+type DrawRecord struct {
+	PrizeNo int `json:"prize_no"`
+	// Amount is the amount of winners requested by the draw.
+	Amount int `json:"amount"`
+	// Commitment is the hex encoded SHA-256 hash of the seed.
+	// It's published before the draw.
+	Commitment string `json:"commitment"`
+	// Seed is the hex encoded secret seed. It's revealed after the draw.
+	Seed string `json:"seed"`
+	// Attribute and Stratum are the attribute and the stratum value of a
+	// stratified draw. Each stratum is drawn with its own seed derived from
+	// the revealed one: SHA-256(seed || stratum value).
+	Attribute string `json:"attribute,omitempty"`
+	Stratum   string `json:"stratum,omitempty"`
+	// EligibleIDs are the sorted IDs of the available participants.
+	EligibleIDs []string `json:"eligible_ids"`
+	// Weights are the weights of the eligible participants in the same order.
+	// It's empty if all weights are the default weight(1).
+	Weights []int `json:"weights,omitempty"`
+	// Caps are the group caps applied to the draw.
+	Caps []CapRecord `json:"caps,omitempty"`
+	// WinnerIDs are the IDs of the winners in the drawn order.
+	WinnerIDs []string `json:"winner_ids"`
+	DrawnAt   string   `json:"drawn_at"`
+}
+
+// CapRecord is the record of a group cap applied to a commit-reveal draw.
+type CapRecord struct {
+	// Groups are the groups of the eligible participants in the same order.
+	// Empty group means not capped.
+	Groups []string `json:"groups"`
+	// Remaining maps group to the remaining amount of winners from it.
+	Remaining map[string]int `json:"remaining"`
+}
+
+const (
+	seedSize = 32
+)
+
+var (
+	ErrCommitmentNotMatch = fmt.Errorf("seed does not match the commitment")
+	ErrSeed               = fmt.Errorf("incorrect seed")
+	ErrVerifyWinners      = fmt.Errorf("winners do not match the re-run draw")
+)
+
+func computeCommitment(seed []byte) string {
+	sum := sha256.Sum256(seed)
+	return hex.EncodeToString(sum[:])
+}
+
+// Commit generates a secret seed for the next draw of the given prize and
+// returns its commitment(hex encoded SHA-256 hash of the seed).
+// The commitment should be published before the draw.
+// The next Draw or Redraw of the prize uses the seed and reveals it in the
+// draw record. If a commitment is already pending for the prize,
+// it returns the pending one.
+//
+// Pending seeds are kept in memory only and are not saved.
+func (l *Lottery) Commit(prizeNo int) (string, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if _, ok := l.prizes[prizeNo]; !ok {
+		return "", ErrPrizeNo
+	}
+
+	if seed, ok := l.seeds[prizeNo]; ok {
+		return computeCommitment(seed), nil
+	}
+
+	seed := make([]byte, seedSize)
+	if _, err := rand.Read(seed); err != nil {
+		return "", err
+	}
+
+	l.seeds[prizeNo] = seed
+	return computeCommitment(seed), nil
+}
+
+// Commitment returns the pending commitment of the given prize.
+// It returns an empty string if no commitment is pending.
+func (l *Lottery) Commitment(prizeNo int) string {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	seed, ok := l.seeds[prizeNo]
+	if !ok {
+		return ""
+	}
+	return computeCommitment(seed)
+}
+
+// DrawRecords returns the commit-reveal draw records of the given prize.
+func (l *Lottery) DrawRecords(prizeNo int) []DrawRecord {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if _, ok := l.drawRecords[prizeNo]; !ok {
+		return []DrawRecord{}
+	}
+
+	return l.drawRecords[prizeNo]
+}
+
+func participantIDs(participants []Participant) []string {
+	IDs := []string{}
+
+	for _, p := range participants {
+		IDs = append(IDs, p.ID)
+	}
+
+	return IDs
+}
+
+// participantWeights returns the weights of the participants.
+// It returns nil if all weights are the default weight(1).
+func participantWeights(participants []Participant) []int {
+	weighted := false
+	weights := []int{}
+
+	for _, p := range participants {
+		if p.weight() != 1 {
+			weighted = true
+		}
+		weights = append(weights, p.weight())
+	}
+
+	if !weighted {
+		return nil
+	}
+	return weights
+}
+
+// drawSeed returns the seed of the RNG used by the draw.
+// Strata of a stratified draw use the seeds derived from the committed seed
+// and the stratum values, so their draws are not correlated.
+// Other draws use the committed seed.
+func drawSeed(seed []byte, attribute string, stratum string) []byte {
+	if attribute == "" {
+		return seed
+	}
+
+	h := sha256.New()
+	h.Write(seed)
+	h.Write([]byte(stratum))
+	return h.Sum(nil)
+}
+
+// newDrawRecord returns the record of the commit-reveal draw which reveals the seed.
+// The attribute and the stratum are empty if it's not a stratified draw.
+// The eligible participants should be sorted by ID.
+func newDrawRecord(prizeNo int, amount int, seed []byte, attribute string, stratum string, eligible []Participant, caps []CapRecord, winners []Participant) DrawRecord {
+	return DrawRecord{
+		prizeNo,
+		amount,
+		computeCommitment(seed),
+		hex.EncodeToString(seed),
+		attribute,
+		stratum,
+		participantIDs(eligible),
+		participantWeights(eligible),
+		caps,
+		participantIDs(winners),
+		time.Now().Format("2006-01-02 15:04:05"),
+	}
+}
+
+// Verify re-runs the draw of the record with the revealed seed and eligible IDs.
+// The seed of a stratum is derived as DrawRecord describes.
+// It returns nil if the seed matches the commitment and the re-run draw
+// produces the same winners.
+func Verify(record DrawRecord) error {
+	seed, err := hex.DecodeString(record.Seed)
+	if err != nil || len(seed) == 0 {
+		return ErrSeed
+	}
+
+	if computeCommitment(seed) != record.Commitment {
+		return ErrCommitmentNotMatch
+	}
+
+	if len(record.Weights) != 0 && len(record.Weights) != len(record.EligibleIDs) {
+		return ErrParticipantWeight
+	}
+
+	participants := []Participant{}
+	for i, ID := range record.EligibleIDs {
+		p := Participant{ID: ID}
+		if len(record.Weights) != 0 {
+			p.Weight = record.Weights[i]
+		}
+		participants = append(participants, p)
+	}
+
+	caps := []*capState{}
+	for _, c := range record.Caps {
+		if len(c.Groups) != len(record.EligibleIDs) {
+			return ErrGroupCap
+		}
+
+		s := &capState{"", 0, make(map[string]string), make(map[string]int)}
+		for i, g := range c.Groups {
+			if g != "" {
+				s.groups[record.EligibleIDs[i]] = g
+			}
+		}
+		for g, n := range c.Remaining {
+			s.remaining[g] = n
+		}
+		caps = append(caps, s)
+	}
+
+	sort.Slice(participants, func(i, j int) bool {
+		return participants[i].ID < participants[j].ID
+	})
+
+	rng := NewSeededRNG(drawSeed(seed, record.Attribute, record.Stratum))
+	winnerIDs := participantIDs(draw(rng, record.Amount, participants, caps))
+	if len(winnerIDs) != len(record.WinnerIDs) {
+		return ErrVerifyWinners
+	}
+
+	for i, ID := range winnerIDs {
+		if ID != record.WinnerIDs[i] {
+			return ErrVerifyWinners
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,56 @@
+package lottery_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/northbright/lottery-go/lottery"
+)
+
+func TestCommitRevealVerify(t *testing.T) {
+	l := newSeededLottery(t, "commit")
+
+	commitment, err := l.Commit(4)
+	if err != nil {
+		t.Fatalf("Commit() error: %v", err)
+	}
+
+	if c := l.Commitment(4); c != commitment {
+		t.Fatalf("Commitment() = %v, want %v", c, commitment)
+	}
+
+	if _, err := l.Draw(4); err != nil {
+		t.Fatalf("Draw() error: %v", err)
+	}
+
+	if c := l.Commitment(4); c != "" {
+		t.Errorf("commitment is still pending after draw: %v", c)
+	}
+
+	records := l.DrawRecords(4)
+	if len(records) != 1 {
+		t.Fatalf("len(DrawRecords()) = %v, want 1", len(records))
+	}
+
+	record := records[0]
+	if record.Commitment != commitment {
+		t.Errorf("record commitment = %v, want %v", record.Commitment, commitment)
+	}
+
+	if err := lottery.Verify(record); err != nil {
+		t.Errorf("Verify() error: %v", err)
+	}
+
+	// Tamper the winners.
+	record.WinnerIDs[0], record.WinnerIDs[1] = record.WinnerIDs[1], record.WinnerIDs[0]
+	if err := lottery.Verify(record); err != lottery.ErrVerifyWinners {
+		t.Errorf("Verify() of tampered winners = %v, want %v", err, lottery.ErrVerifyWinners)
+	}
+
+	// Tamper the seed.
+	record = l.DrawRecords(4)[0]
+	record.Seed = strings.Repeat("ab", 32)
+	if err := lottery.Verify(record); err != lottery.ErrCommitmentNotMatch {
+		t.Errorf("Verify() of tampered seed = %v, want %v", err, lottery.ErrCommitmentNotMatch)
+	}
+}
@@ -0,0 +1,152 @@
+package lottery_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/northbright/lottery-go/lottery"
+)
+
+func TestWeightedDraw(t *testing.T) {
+	csv := `ID, Name, Weight
+1,Alice,1000
+2,Bob,1
+3,Carol,1
+`
+	wins := 0
+	rng := lottery.NewSeededRNG([]byte("weights"))
+
+	for i := 0; i < 100; i++ {
+		l := lottery.New("weighted", lottery.WithRNG(rng))
+		if err := l.LoadParticipantsCSV(strings.NewReader(csv)); err != nil {
+			t.Fatalf("LoadParticipantsCSV() error: %v", err)
+		}
+		l.SetPrize(1, "1st prize", 1, "")
+
+		winners, err := l.Draw(1)
+		if err != nil {
+			t.Fatalf("Draw() error: %v", err)
+		}
+		if winners[0].ID == "1" {
+			wins++
+		}
+	}
+
+	if wins < 90 {
+		t.Errorf("participant with weight 1000 won %v of 100 draws", wins)
+	}
+}
+
+func TestWeightedDrawWithoutReplacement(t *testing.T) {
+	csv := `ID, Name, Weight
+1,Alice,5
+2,Bob,1
+3,Carol,
+`
+	l := lottery.New("weighted", lottery.WithRNG(lottery.NewSeededRNG([]byte("once"))))
+	if err := l.LoadParticipantsCSV(strings.NewReader(csv)); err != nil {
+		t.Fatalf("LoadParticipantsCSV() error: %v", err)
+	}
+	l.SetPrize(1, "1st prize", 3, "")
+
+	winners, err := l.Draw(1)
+	if err != nil {
+		t.Fatalf("Draw() error: %v", err)
+	}
+
+	seen := make(map[string]bool)
+	for _, w := range winners {
+		if seen[w.ID] {
+			t.Errorf("%v won more than once", w.ID)
+		}
+		seen[w.ID] = true
+	}
+	if len(seen) != 3 {
+		t.Errorf("got %v winners, want 3", len(seen))
+	}
+}
+
+func TestDrawBatch(t *testing.T) {
+	l := newSeededLottery(t, "batch")
+
+	// Prize no.4 has 8 places: draw 3 + 3 + 2.
+	for i, want := range []int{3, 3, 2} {
+		winners, round, err := l.DrawBatch(4, 3)
+		if err != nil {
+			t.Fatalf("DrawBatch() error: %v", err)
+		}
+		if len(winners) != want {
+			t.Errorf("round %v: got %v winners, want %v", i+1, len(winners), want)
+		}
+		if round != i+1 {
+			t.Errorf("round = %v, want %v", round, i+1)
+		}
+		for _, w := range winners {
+			if r := l.WinnerRound(4, w.ID); r != round {
+				t.Errorf("WinnerRound(4, %v) = %v, want %v", w.ID, r, round)
+			}
+		}
+	}
+
+	if len(l.Winners(4)) != 8 {
+		t.Errorf("got %v winners, want 8", len(l.Winners(4)))
+	}
+
+	if _, _, err := l.DrawBatch(4, 1); err != lottery.ErrPrizeDrawn {
+		t.Errorf("DrawBatch() of a drawn prize = %v, want %v", err, lottery.ErrPrizeDrawn)
+	}
+}
+
+func TestRepeatWinners(t *testing.T) {
+	csv := `ID,Name
+1,Alice
+2,Bob
+3,Carol
+`
+	l := lottery.New("repeat", lottery.WithRNG(lottery.NewSeededRNG([]byte("repeat"))))
+	if err := l.LoadParticipantsCSV(strings.NewReader(csv)); err != nil {
+		t.Fatalf("LoadParticipantsCSV() error: %v", err)
+	}
+
+	// Consolation gift for everyone, then a grand prize over the whole pool.
+	l.SetPrize(9, "Consolation gift", 3, "")
+	l.SetPrize(8, "Souvenir", 1, "")
+	l.SetPrize(1, "Grand prize", 3, "")
+	if err := l.SetPrizeRepeatWinners(1, lottery.RepeatPrizes, []int{9}); err != nil {
+		t.Fatalf("SetPrizeRepeatWinners() error: %v", err)
+	}
+
+	if _, err := l.Draw(9); err != nil {
+		t.Fatalf("Draw(9) error: %v", err)
+	}
+	if n := len(l.AvailableParticipants(8)); n != 0 {
+		t.Errorf("prize 8 has %v available participants, want 0", n)
+	}
+	if n := len(l.AvailableParticipants(1)); n != 3 {
+		t.Errorf("prize 1 has %v available participants, want 3", n)
+	}
+
+	winners, err := l.Draw(1)
+	if err != nil {
+		t.Fatalf("Draw(1) error: %v", err)
+	}
+	if len(winners) != 3 {
+		t.Errorf("got %v winners of prize 1, want 3", len(winners))
+	}
+
+	if prizeNos := l.WinnerPrizes(winners[0].ID); len(prizeNos) != 2 {
+		t.Errorf("WinnerPrizes(%v) = %v, want [1 9]", winners[0].ID, prizeNos)
+	}
+
+	// Revoke the grand prize only.
+	if err := l.Revoke(1, []lottery.Participant{winners[0].Participant}, lottery.ReasonMistake); err != nil {
+		t.Fatalf("Revoke() error: %v", err)
+	}
+	if prizeNos := l.WinnerPrizes(winners[0].ID); len(prizeNos) != 1 || prizeNos[0] != 9 {
+		t.Errorf("WinnerPrizes(%v) = %v, want [9]", winners[0].ID, prizeNos)
+	}
+
+	if err := l.SetPrizeRepeatWinners(1, "some", nil); err != lottery.ErrRepeatWinners {
+		t.Errorf("SetPrizeRepeatWinners() with unknown mode = %v, want %v", err, lottery.ErrRepeatWinners)
+	}
+}
@@ -0,0 +1,249 @@
+package lottery
+
+import (
+	"fmt"
+)
+
+// GroupCap caps the amount of winners from the same group.
+// The group of a participant is the value of the attribute.
+// Participants without the attribute are not capped.
+type GroupCap struct {
+	// Attribute is the name of the participant attribute used as the group.
+	Attribute string `json:"attribute"`
+	// Max is the max amount of winners from the same group.
+	Max int `json:"max"`
+}
+
+// GroupCapError is returned when the amount of winners can not be drawn
+// because of a group cap.
+type GroupCapError struct {
+	PrizeNo   int
+	Attribute string
+	Max       int
+	// Amount is the requested amount of winners.
+	Amount int
+	// Available is the max amount of winners which can be drawn
+	// under the cap.
+	Available int
+}
+
+func (e *GroupCapError) Error() string {
+	return fmt.Sprintf("group cap(attribute: %v, max: %v) can not be satisfied for prize %v: %v winners requested, %v available",
+		e.Attribute,
+		e.Max,
+		e.PrizeNo,
+		e.Amount,
+		e.Available,
+	)
+}
+
+var (
+	ErrGroupCap = fmt.Errorf("incorrect group cap")
+)
+
+// capState is the state of a group cap during a draw.
+type capState struct {
+	attribute string
+	max       int
+	// groups maps participant ID to group.
+	groups map[string]string
+	// remaining maps group to the remaining amount of winners from it.
+	remaining map[string]int
+}
+
+// SetGroupCap caps the amount of winners of all prizes from the same group.
+// The max <= 0 removes the cap.
+func (l *Lottery) SetGroupCap(attribute string, max int) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	var groupCap *GroupCap
+	if max > 0 {
+		if attribute == "" {
+			return ErrGroupCap
+		}
+		groupCap = &GroupCap{attribute, max}
+	}
+
+	if err := l.record(Event{Type: EventSetGroupCap, GroupCap: groupCap, Meta: l.meta}); err != nil {
+		return err
+	}
+
+	l.groupCap = groupCap
+	return nil
+}
+
+// GroupCap returns the group cap of the whole lottery.
+// It returns nil if there's no cap.
+func (l *Lottery) GroupCap() *GroupCap {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return l.groupCap
+}
+
+// SetPrizeGroupCap caps the amount of winners of the prize from the same group.
+// The max <= 0 removes the cap.
+func (l *Lottery) SetPrizeGroupCap(prizeNo int, attribute string, max int) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	prize, ok := l.prizes[prizeNo]
+	if !ok {
+		return ErrPrizeNo
+	}
+
+	if max <= 0 {
+		prize.GroupCap = nil
+	} else {
+		if attribute == "" {
+			return ErrGroupCap
+		}
+		prize.GroupCap = &GroupCap{attribute, max}
+	}
+
+	if err := l.record(Event{Type: EventSetPrizeGroupCap, Prize: &prize, Meta: l.meta}); err != nil {
+		return err
+	}
+
+	l.prizes[prizeNo] = prize
+	return nil
+}
+
+func newCapState(c *GroupCap, participants []Participant, winners []Winner) *capState {
+	s := &capState{
+		c.Attribute,
+		c.Max,
+		make(map[string]string),
+		make(map[string]int),
+	}
+
+	for _, p := range participants {
+		if g := p.Attributes[c.Attribute]; g != "" {
+			s.groups[p.ID] = g
+			s.remaining[g] = c.Max
+		}
+	}
+
+	for _, w := range winners {
+		if g := w.Attributes[c.Attribute]; g != "" {
+			if _, ok := s.remaining[g]; ok {
+				s.remaining[g]--
+			}
+		}
+	}
+
+	return s
+}
+
+// groupCaps returns the states of the group caps for drawing the prize.
+// The prize cap counts the existing winners of the prize.
+// The lottery cap counts the existing winners of all prizes.
+func (l *Lottery) groupCaps(prizeNo int, participants []Participant) []*capState {
+	caps := []*capState{}
+
+	if c := l.prizes[prizeNo].GroupCap; c != nil {
+		caps = append(caps, newCapState(c, participants, l.winners[prizeNo]))
+	}
+
+	if c := l.groupCap; c != nil {
+		winners := []Winner{}
+		for _, w := range l.winners {
+			winners = append(winners, w...)
+		}
+		caps = append(caps, newCapState(c, participants, winners))
+	}
+
+	return caps
+}
+
+// capacity returns the max amount of winners which can be drawn
+// from the participants under the cap.
+func (s *capState) capacity(participants []Participant) int {
+	sizes := make(map[string]int)
+	n := 0
+
+	for _, p := range participants {
+		g, ok := s.groups[p.ID]
+		if !ok {
+			n++
+			continue
+		}
+		sizes[g]++
+	}
+
+	for g, size := range sizes {
+		remaining := s.remaining[g]
+		if remaining < 0 {
+			remaining = 0
+		}
+		if size < remaining {
+			n += size
+		} else {
+			n += remaining
+		}
+	}
+
+	return n
+}
+
+// full reports whether the group of the participant reaches the cap.
+func (s *capState) full(p Participant) bool {
+	g, ok := s.groups[p.ID]
+	if !ok {
+		return false
+	}
+	return s.remaining[g] <= 0
+}
+
+// removeCapped removes the participants whose groups reach any cap.
+// It keeps the order of the participants.
+func removeCapped(participants []Participant, caps []*capState) []Participant {
+	s := []Participant{}
+
+	for _, p := range participants {
+		capped := false
+		for _, c := range caps {
+			if c.full(p) {
+				capped = true
+				break
+			}
+		}
+		if !capped {
+			s = append(s, p)
+		}
+	}
+
+	return s
+}
+
+// checkGroupCaps checks if the amount of winners can be drawn under the caps.
+func checkGroupCaps(prizeNo int, amount int, participants []Participant, caps []*capState) error {
+	if amount > len(participants) {
+		amount = len(participants)
+	}
+
+	for _, c := range caps {
+		if n := c.capacity(participants); n < amount {
+			return &GroupCapError{prizeNo, c.attribute, c.max, amount, n}
+		}
+	}
+
+	return nil
+}
+
+// record returns the record of the cap for the eligible participants.
+func (s *capState) record(eligible []Participant) CapRecord {
+	groups := []string{}
+	remaining := make(map[string]int)
+
+	for _, p := range eligible {
+		g := s.groups[p.ID]
+		groups = append(groups, g)
+		if g != "" {
+			remaining[g] = s.remaining[g]
+		}
+	}
+
+	return CapRecord{groups, remaining}
+}
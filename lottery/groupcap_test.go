@@ -0,0 +1,98 @@
+package lottery_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/northbright/lottery-go/lottery"
+)
+
+const groupCSV = `ID,Name,Team
+1,Alice,A
+2,Bob,A
+3,Carol,A
+4,Dave,A
+5,Eve,B
+6,Frank,B
+7,Grace,C
+8,Heidi,
+`
+
+func TestPrizeGroupCap(t *testing.T) {
+	l := lottery.New("cap", lottery.WithRNG(lottery.NewSeededRNG([]byte("cap"))))
+	if err := l.LoadParticipantsCSV(strings.NewReader(groupCSV)); err != nil {
+		t.Fatalf("LoadParticipantsCSV() error: %v", err)
+	}
+	l.SetPrize(1, "1st prize", 5, "")
+	if err := l.SetPrizeGroupCap(1, "Team", 1); err != nil {
+		t.Fatalf("SetPrizeGroupCap() error: %v", err)
+	}
+
+	// Team A, B, C and Heidi(no team): at most 4 winners.
+	_, err := l.Draw(1)
+	e, ok := err.(*lottery.GroupCapError)
+	if !ok {
+		t.Fatalf("Draw() error = %v, want *GroupCapError", err)
+	}
+	if e.Available != 4 {
+		t.Errorf("GroupCapError.Available = %v, want 4", e.Available)
+	}
+
+	l.SetPrize(1, "1st prize", 4, "")
+	winners, err := l.Draw(1)
+	if err != nil {
+		t.Fatalf("Draw() error: %v", err)
+	}
+
+	teams := make(map[string]int)
+	for _, w := range winners {
+		teams[w.Attributes["Team"]]++
+	}
+	for team, n := range teams {
+		if n > 1 {
+			t.Errorf("team %q has %v winners", team, n)
+		}
+	}
+
+	// Revoke a winner and redraw: the cap is kept.
+	if err := l.Revoke(1, []lottery.Participant{winners[0].Participant}, lottery.ReasonMistake); err != nil {
+		t.Fatalf("Revoke() error: %v", err)
+	}
+	newWinners, err := l.Redraw(1, 1)
+	if err != nil {
+		t.Fatalf("Redraw() error: %v", err)
+	}
+	if newWinners[0].Attributes["Team"] != winners[0].Attributes["Team"] {
+		t.Errorf("redraw winner %v breaks the cap", newWinners[0])
+	}
+}
+
+func TestLotteryGroupCap(t *testing.T) {
+	l := lottery.New("cap", lottery.WithRNG(lottery.NewSeededRNG([]byte("lottery cap"))))
+	if err := l.LoadParticipantsCSV(strings.NewReader(groupCSV)); err != nil {
+		t.Fatalf("LoadParticipantsCSV() error: %v", err)
+	}
+	l.SetPrize(2, "2nd prize", 3, "")
+	l.SetPrize(1, "1st prize", 2, "")
+	if err := l.SetGroupCap("Team", 2); err != nil {
+		t.Fatalf("SetGroupCap() error: %v", err)
+	}
+
+	for _, prizeNo := range []int{2, 1} {
+		if _, err := l.Draw(prizeNo); err != nil {
+			t.Fatalf("Draw(%v) error: %v", prizeNo, err)
+		}
+	}
+
+	teams := make(map[string]int)
+	for _, winners := range l.AllWinners() {
+		for _, w := range winners {
+			teams[w.Attributes["Team"]]++
+		}
+	}
+	for team, n := range teams {
+		if team != "" && n > 2 {
+			t.Errorf("team %q has %v winners", team, n)
+		}
+	}
+}
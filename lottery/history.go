@@ -0,0 +1,262 @@
+package lottery
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// HistoryEntry is a win of a participant in a previous event.
+type HistoryEntry struct {
+	Event   string `json:"event"`
+	PrizeNo int    `json:"prize_no"`
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+}
+
+// History is the win history of previous events.
+// It's used by history rules of prizes to exclude or down-weight
+// previous winners.
+type History struct {
+	// events are the event names in chronological order.
+	events []string
+	// entries maps event name to the wins of the event.
+	entries map[string][]HistoryEntry
+	mutex   *sync.Mutex
+}
+
+type historyData struct {
+	Events  []string                  `json:"events"`
+	Entries map[string][]HistoryEntry `json:"entries"`
+}
+
+var (
+	ErrHistoryEvent = fmt.Errorf("incorrect history event")
+)
+
+// NewHistory returns an empty win history.
+func NewHistory() *History {
+	return &History{
+		[]string{},
+		make(map[string][]HistoryEntry),
+		&sync.Mutex{},
+	}
+}
+
+// ImportSaveData imports the winners of the saved data of a lottery as
+// an event. Events should be imported in chronological order.
+// Importing an existing event replaces its wins but keeps its order.
+func (h *History) ImportSaveData(event string, data SaveData) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if event == "" {
+		return ErrHistoryEvent
+	}
+
+	entries := []HistoryEntry{}
+	for _, prize := range prizeMapToSlice(data.Prizes, false) {
+		for _, winner := range data.Winners[prize.No] {
+			entries = append(entries, HistoryEntry{event, prize.No, winner.ID, winner.Name})
+		}
+	}
+
+	if _, ok := h.entries[event]; !ok {
+		h.events = append(h.events, event)
+	}
+	h.entries[event] = entries
+	return nil
+}
+
+// ImportSaveFile imports the saved data file of a lottery as an event.
+// See ImportSaveData for more information.
+func (h *History) ImportSaveFile(event string, file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	data, err := DecodeSaveData(f)
+	if err != nil {
+		return err
+	}
+
+	if fmt.Sprintf("%X", computeWinnersHash(data.Winners)) != data.Checksum {
+		return ErrChecksum
+	}
+
+	return h.ImportSaveData(event, data)
+}
+
+// ImportStore imports the saved data of the lottery in the store as an event.
+// See ImportSaveData for more information.
+func (h *History) ImportStore(event string, s Store, name string) error {
+	data, err := s.Load(name)
+	if err != nil {
+		return err
+	}
+
+	if fmt.Sprintf("%X", computeWinnersHash(data.Winners)) != data.Checksum {
+		return ErrChecksum
+	}
+
+	return h.ImportSaveData(event, data)
+}
+
+// Events returns the event names in chronological order.
+func (h *History) Events() []string {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	events := make([]string, len(h.events))
+	copy(events, h.events)
+	return events
+}
+
+// Wins returns the wins of the participant in the last events.
+// lastEvents <= 0 means all events.
+func (h *History) Wins(ID string, lastEvents int) []HistoryEntry {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	return h.wins(ID, lastEvents, nil)
+}
+
+// wins returns the wins of the participant in the last events.
+// IDs of the entries are normalized by normalizeID if it's not nil.
+func (h *History) wins(ID string, lastEvents int, normalizeID *IDNormalization) []HistoryEntry {
+	wins := []HistoryEntry{}
+
+	events := h.events
+	if lastEvents > 0 && lastEvents < len(events) {
+		events = events[len(events)-lastEvents:]
+	}
+
+	for _, event := range events {
+		for _, entry := range h.entries[event] {
+			entryID := entry.ID
+			if normalizeID != nil {
+				entryID = normalizeID.normalize(entryID)
+			}
+
+			if entryID == ID {
+				wins = append(wins, entry)
+			}
+		}
+	}
+
+	return wins
+}
+
+// won reports whether the participant won a prize with no <= maxPrizeNo
+// in the last events. maxPrizeNo <= 0 means any prize.
+func (h *History) won(ID string, maxPrizeNo int, lastEvents int, normalizeID *IDNormalization) bool {
+	for _, entry := range h.wins(ID, lastEvents, normalizeID) {
+		if maxPrizeNo <= 0 || entry.PrizeNo <= maxPrizeNo {
+			return true
+		}
+	}
+	return false
+}
+
+// Save saves the history as JSON.
+func (h *History) Save(w io.Writer) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	data := historyData{h.events, h.entries}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "    ")
+	return enc.Encode(&data)
+}
+
+// Load loads the history from JSON saved by Save.
+func (h *History) Load(r io.Reader) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	data := historyData{}
+	if err := json.NewDecoder(r).Decode(&data); err != nil {
+		return err
+	}
+
+	h.events = data.Events
+	h.entries = data.Entries
+
+	if h.events == nil {
+		h.events = []string{}
+	}
+
+	if h.entries == nil {
+		h.entries = make(map[string][]HistoryEntry)
+	}
+
+	return nil
+}
+
+// SetHistory sets the win history of previous events used by history rules.
+func (l *Lottery) SetHistory(h *History) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.history = h
+}
+
+// applyHistoryRules removes or re-weights the participants by the history
+// rules. If there're weight rules, weights of the participants are replaced
+// with the effective weights(scaled by 100), which are at least 1.
+// History rules are ignored if the lottery has no history.
+func (l *Lottery) applyHistoryRules(rules []Rule, participants map[string]Participant) {
+	if l.history == nil {
+		return
+	}
+
+	h := l.history
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	weighted := false
+	for _, rule := range rules {
+		if rule.Op == RuleHistoryWeight || rule.Op == RuleNoHistoryWeight {
+			weighted = true
+		}
+	}
+
+	for ID, p := range participants {
+		// Scale weights by 100 to apply weight percents without rounding.
+		if weighted {
+			p.Weight = p.weight() * 100
+		}
+
+		for _, rule := range rules {
+			switch rule.Op {
+			case RuleHistoryExclude:
+				if h.won(ID, rule.MaxPrizeNo, rule.LastEvents, l.normalizeID) {
+					delete(participants, ID)
+				}
+			case RuleHistoryWeight:
+				if h.won(ID, rule.MaxPrizeNo, rule.LastEvents, l.normalizeID) {
+					p.Weight = p.Weight * rule.WeightPercent / 100
+				}
+			case RuleNoHistoryWeight:
+				if !h.won(ID, rule.MaxPrizeNo, rule.LastEvents, l.normalizeID) {
+					p.Weight = p.Weight * rule.WeightPercent / 100
+				}
+			}
+		}
+
+		// Stacked weight rules may round the weight down to 0.
+		// Keep the participant eligible with the minimum weight.
+		if weighted && p.Weight < 1 {
+			p.Weight = 1
+		}
+
+		if _, ok := participants[ID]; ok {
+			participants[ID] = p
+		}
+	}
+}
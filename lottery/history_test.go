@@ -0,0 +1,168 @@
+package lottery_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/northbright/lottery-go/lottery"
+)
+
+const historyCSV = `ID,Name
+1,Alice
+2,Bob
+3,Carol
+4,Dave
+`
+
+// newEvent returns the saved data of an event in which the given
+// participants won the prize.
+func newEvent(t *testing.T, name string, prizeNo int, IDs ...string) lottery.SaveData {
+	l := lottery.New(name)
+	if err := l.LoadParticipantsCSV(strings.NewReader(historyCSV)); err != nil {
+		t.Fatalf("LoadParticipantsCSV() error: %v", err)
+	}
+	l.SetPrize(prizeNo, "prize", len(IDs), "")
+
+	blacklist := []string{}
+	for _, p := range l.Participants() {
+		found := false
+		for _, ID := range IDs {
+			if p.ID == ID {
+				found = true
+			}
+		}
+		if !found {
+			blacklist = append(blacklist, p.ID)
+		}
+	}
+	l.SetBlacklist(prizeNo+1, blacklist)
+
+	if _, err := l.Draw(prizeNo); err != nil {
+		t.Fatalf("Draw() error: %v", err)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := l.Save(buf); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+
+	data := lottery.SaveData{}
+	if err := json.Unmarshal(buf.Bytes(), &data); err != nil {
+		t.Fatalf("decode JSON error: %v", err)
+	}
+	return data
+}
+
+func TestHistoryRules(t *testing.T) {
+	h := lottery.NewHistory()
+	// Alice won the 1st prize 3 events ago, Bob won the 2nd prize last event,
+	// Carol won the 5th prize last event.
+	if err := h.ImportSaveData("2023", newEvent(t, "party", 1, "1")); err != nil {
+		t.Fatalf("ImportSaveData() error: %v", err)
+	}
+	if err := h.ImportSaveData("2024", newEvent(t, "party", 5, "4")); err != nil {
+		t.Fatalf("ImportSaveData() error: %v", err)
+	}
+	if err := h.ImportSaveData("2025", newEvent(t, "party", 2, "2")); err != nil {
+		t.Fatalf("ImportSaveData() error: %v", err)
+	}
+
+	if events := h.Events(); len(events) != 3 || events[2] != "2025" {
+		t.Errorf("Events() = %v", events)
+	}
+
+	l := lottery.New("party")
+	if err := l.LoadParticipantsCSV(strings.NewReader(historyCSV)); err != nil {
+		t.Fatalf("LoadParticipantsCSV() error: %v", err)
+	}
+	l.SetPrize(1, "1st prize", 1, "")
+	l.SetHistory(h)
+
+	// Exclude anyone who won prize <= 2 in the last 2 events: Bob.
+	rules := []lottery.Rule{{Op: lottery.RuleHistoryExclude, MaxPrizeNo: 2, LastEvents: 2}}
+	if err := l.SetPrizeRules(1, rules); err != nil {
+		t.Fatalf("SetPrizeRules() error: %v", err)
+	}
+
+	available := l.AvailableParticipants(1)
+	if len(available) != 3 {
+		t.Fatalf("AvailableParticipants() = %v, want 3 participants", available)
+	}
+	for _, p := range available {
+		if p.ID == "2" {
+			t.Errorf("Bob should be excluded")
+		}
+	}
+
+	// Give a 2x weight to people who have never won.
+	rules = []lottery.Rule{{Op: lottery.RuleNoHistoryWeight, WeightPercent: 200}}
+	if err := l.SetPrizeRules(1, rules); err != nil {
+		t.Fatalf("SetPrizeRules() error: %v", err)
+	}
+	for _, p := range l.AvailableParticipants(1) {
+		if p.Weight > 1 {
+			t.Errorf("AvailableParticipants() returns effective weight: %v", p)
+		}
+	}
+
+	winners, err := l.Draw(1)
+	if err != nil {
+		t.Fatalf("Draw() error: %v", err)
+	}
+	if winners[0].Weight > 1 {
+		t.Errorf("winner has effective weight: %v", winners[0])
+	}
+}
+
+// totalRNG records the total weights passed to Intn.
+type totalRNG struct {
+	totals []int
+}
+
+func (r *totalRNG) Intn(n int) int {
+	r.totals = append(r.totals, n)
+	return 0
+}
+
+func TestStackedWeightRules(t *testing.T) {
+	h := lottery.NewHistory()
+	// Alice won the 1st prize last event.
+	if err := h.ImportSaveData("2025", newEvent(t, "party", 1, "1")); err != nil {
+		t.Fatalf("ImportSaveData() error: %v", err)
+	}
+
+	tests := []struct {
+		op    string
+		total int
+	}{
+		// Alice: 100 * 1% * 1% rounds to 0 and is clamped to 1.
+		// Others: 100 each.
+		{lottery.RuleHistoryWeight, 1 + 3*100},
+		// Bob, Carol and Dave: clamped to 1 each. Alice: 100.
+		{lottery.RuleNoHistoryWeight, 3*1 + 100},
+	}
+
+	for _, tt := range tests {
+		rng := &totalRNG{}
+		l := lottery.New("party", lottery.WithRNG(rng))
+		if err := l.LoadParticipantsCSV(strings.NewReader(historyCSV)); err != nil {
+			t.Fatalf("LoadParticipantsCSV() error: %v", err)
+		}
+		l.SetPrize(1, "1st prize", 1, "")
+		l.SetHistory(h)
+
+		rule := lottery.Rule{Op: tt.op, WeightPercent: 1}
+		if err := l.SetPrizeRules(1, []lottery.Rule{rule, rule}); err != nil {
+			t.Fatalf("SetPrizeRules() error: %v", err)
+		}
+
+		if _, err := l.Draw(1); err != nil {
+			t.Fatalf("Draw() error: %v", err)
+		}
+		if len(rng.totals) != 1 || rng.totals[0] != tt.total {
+			t.Errorf("%v: total weights = %v, want %v", tt.op, rng.totals, tt.total)
+		}
+	}
+}
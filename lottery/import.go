@@ -0,0 +1,593 @@
+package lottery
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ImportIssue is an error or a warning of the imported CSV.
+type ImportIssue struct {
+	// Line is the line number in the CSV. The header is line 1.
+	Line int `json:"line"`
+	// Column is the column number starting at 1.
+	// 0 means the issue is of the whole row.
+	Column int `json:"column"`
+	// Field is the header of the column.
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// ImportReport is the validation report of the imported CSV.
+type ImportReport struct {
+	// Rows is the amount of rows except the header.
+	Rows int `json:"rows"`
+	// Imported is the amount of valid participants or prizes.
+	Imported int           `json:"imported"`
+	Errors   []ImportIssue `json:"errors"`
+	Warnings []ImportIssue `json:"warnings"`
+}
+
+// ImportError is the error of the import with the validation report.
+type ImportError struct {
+	// Err is ErrParticipantsCSV or ErrPrizesCSV.
+	Err    error
+	Report ImportReport
+}
+
+var (
+	ErrPrizesCSV = fmt.Errorf("incorrect prizes CSV")
+)
+
+func (issue ImportIssue) String() string {
+	switch {
+	case issue.Column == 0:
+		return fmt.Sprintf("line %v: %v", issue.Line, issue.Message)
+	case issue.Field == "":
+		return fmt.Sprintf("line %v, column %v: %v", issue.Line, issue.Column, issue.Message)
+	default:
+		return fmt.Sprintf("line %v, column %v(%v): %v", issue.Line, issue.Column, issue.Field, issue.Message)
+	}
+}
+
+func newImportReport() ImportReport {
+	return ImportReport{0, 0, []ImportIssue{}, []ImportIssue{}}
+}
+
+// OK reports whether the CSV has no errors and warnings.
+func (r ImportReport) OK() bool {
+	return len(r.Errors) == 0 && len(r.Warnings) == 0
+}
+
+func (e *ImportError) Error() string {
+	issues := append(append([]ImportIssue{}, e.Report.Errors...), e.Report.Warnings...)
+	switch len(issues) {
+	case 0:
+		return e.Err.Error()
+	case 1:
+		return fmt.Sprintf("%v: %v", e.Err, issues[0])
+	default:
+		return fmt.Sprintf("%v: %v(and %v more issues)", e.Err, issues[0], len(issues)-1)
+	}
+}
+
+func (e *ImportError) Unwrap() error {
+	return e.Err
+}
+
+// csvImport reads the rows of the CSV and records the issues.
+type csvImport struct {
+	reader *csv.Reader
+	spec   ImportSpec
+	header []string
+	// columns maps the fields to the columns.
+	columns map[string]int
+	// ignored are the ignored columns.
+	ignored map[int]bool
+	report  ImportReport
+	// malformed means nothing can be imported from the CSV,
+	// e.g. the header is missing or a quote is not closed.
+	malformed bool
+}
+
+func newCSVImport(r io.Reader, spec ImportSpec) *csvImport {
+	reader := csv.NewReader(r)
+	reader.Comma = spec.delimiter()
+	// Check the amount of fields of each row to report the line.
+	reader.FieldsPerRecord = -1
+
+	return &csvImport{
+		reader,
+		spec,
+		nil,
+		make(map[string]int),
+		make(map[int]bool),
+		newImportReport(),
+		false,
+	}
+}
+
+// read reads the next row. It returns false at the end of the CSV or if
+// the CSV is malformed.
+func (c *csvImport) read() ([]string, bool) {
+	row, err := c.reader.Read()
+	if err == io.EOF {
+		return nil, false
+	}
+
+	if err != nil {
+		c.malformed = true
+		if e, ok := err.(*csv.ParseError); ok {
+			c.report.Errors = append(c.report.Errors, ImportIssue{e.Line, 0, "", e.Err.Error()})
+		} else {
+			c.report.Errors = append(c.report.Errors, ImportIssue{0, 0, "", err.Error()})
+		}
+		return nil, false
+	}
+
+	if c.spec.Trim {
+		for i := range row {
+			row[i] = strings.TrimSpace(row[i])
+		}
+	}
+
+	return row, true
+}
+
+// readHeader reads the header with at least minColumns columns.
+func (c *csvImport) readHeader(minColumns int) bool {
+	header, ok := c.read()
+	if !ok {
+		c.malformed = true
+		if len(c.report.Errors) == 0 {
+			c.report.Errors = append(c.report.Errors, ImportIssue{1, 0, "", "header is missing"})
+		}
+		return false
+	}
+
+	if len(header) < minColumns {
+		msg := fmt.Sprintf("header has %v columns, want at least %v", len(header), minColumns)
+		c.report.Errors = append(c.report.Errors, ImportIssue{1, 0, "", msg})
+		c.malformed = true
+		return false
+	}
+
+	c.header = header
+	return true
+}
+
+// mapColumns maps the fields to the columns of the header by the column
+// mapping of the spec. The required fields must be mapped.
+func (c *csvImport) mapColumns(required ...string) bool {
+	for j, h := range c.header {
+		f, ok := c.spec.field(h)
+		if !ok {
+			continue
+		}
+
+		if f == FieldIgnore {
+			c.ignored[j] = true
+			continue
+		}
+
+		if first, ok := c.columns[f]; ok {
+			msg := fmt.Sprintf("duplicate column of %v, first defined at column %v", f, first+1)
+			c.report.Warnings = append(c.report.Warnings, ImportIssue{1, j + 1, strings.TrimSpace(h), msg})
+			continue
+		}
+		c.columns[f] = j
+	}
+
+	for _, f := range required {
+		if _, ok := c.columns[f]; !ok {
+			c.report.Errors = append(c.report.Errors, ImportIssue{1, 0, "", fmt.Sprintf("column of %v is missing", f)})
+			c.malformed = true
+		}
+	}
+
+	return !c.malformed
+}
+
+// mapped reports whether the column is mapped to a field or ignored.
+func (c *csvImport) mapped(col int) bool {
+	if c.ignored[col] {
+		return true
+	}
+
+	for _, j := range c.columns {
+		if j == col {
+			return true
+		}
+	}
+	return false
+}
+
+// value returns the value of the field in the row.
+// It returns -1 as the column if the field is not mapped.
+func (c *csvImport) value(row []string, field string) (string, int) {
+	j, ok := c.columns[field]
+	if !ok || j >= len(row) {
+		return "", -1
+	}
+	return row[j], j
+}
+
+// readRow reads the next data row. Rows with incorrect amount of fields are
+// reported and skipped.
+func (c *csvImport) readRow(validLen func(n int) bool) ([]string, bool) {
+	for {
+		row, ok := c.read()
+		if !ok {
+			return nil, false
+		}
+
+		c.report.Rows++
+		if validLen(len(row)) {
+			return row, true
+		}
+
+		line, _ := c.reader.FieldPos(0)
+		msg := fmt.Sprintf("row has %v fields, header has %v", len(row), len(c.header))
+		c.report.Errors = append(c.report.Errors, ImportIssue{line, 0, "", msg})
+	}
+}
+
+// issue returns the issue of the column of the current row.
+func (c *csvImport) issue(col int, format string, args ...interface{}) ImportIssue {
+	line, _ := c.reader.FieldPos(col)
+
+	field := ""
+	if col < len(c.header) {
+		field = strings.TrimSpace(c.header[col])
+	}
+
+	return ImportIssue{line, col + 1, field, fmt.Sprintf(format, args...)}
+}
+
+func (c *csvImport) errorf(col int, format string, args ...interface{}) {
+	c.report.Errors = append(c.report.Errors, c.issue(col, format, args...))
+}
+
+func (c *csvImport) warnf(col int, format string, args ...interface{}) {
+	c.report.Warnings = append(c.report.Warnings, c.issue(col, format, args...))
+}
+
+// parseParticipantsCSV parses and validates the participants CSV.
+// See LoadParticipantsCSV for the format.
+// It returns the valid participants, the report and whether the CSV is
+// malformed.
+func parseParticipantsCSV(r io.Reader, spec ImportSpec) (map[string]Participant, ImportReport, bool) {
+	participants := make(map[string]Participant)
+
+	c := newCSVImport(r, spec)
+	if len(spec.Columns) > 0 {
+		if !c.readHeader(2) || !c.mapColumns(FieldID, FieldName) {
+			return participants, c.report, true
+		}
+	} else {
+		// The first 2 columns are ID and Name.
+		if !c.readHeader(2) {
+			return participants, c.report, true
+		}
+		c.columns[FieldID] = 0
+		c.columns[FieldName] = 1
+	}
+
+	header := c.header
+	if _, ok := c.columns[FieldWeight]; !ok {
+		for j := 0; j < len(header); j++ {
+			if !c.mapped(j) && strings.EqualFold(strings.TrimSpace(header[j]), "weight") {
+				c.columns[FieldWeight] = j
+			}
+		}
+	}
+
+	// attributeCols are the columns of attributes.
+	attributeCols := []int{}
+	names := make(map[string]int)
+	for j := 0; j < len(header); j++ {
+		if c.mapped(j) {
+			continue
+		}
+
+		// Columns with blank header are skipped, because attributes are
+		// named by the header.
+		name := strings.TrimSpace(header[j])
+		if name == "" {
+			c.report.Warnings = append(c.report.Warnings, ImportIssue{1, j + 1, "", "attribute name is blank, column is skipped"})
+			continue
+		}
+
+		if first, ok := names[name]; ok {
+			msg := fmt.Sprintf("duplicate attribute %v, first defined at column %v", name, first+1)
+			c.report.Warnings = append(c.report.Warnings, ImportIssue{1, j + 1, name, msg})
+		} else {
+			names[name] = j
+		}
+		attributeCols = append(attributeCols, j)
+	}
+
+	// lines maps ID to the line of the participant.
+	lines := make(map[string]int)
+	for {
+		row, ok := c.readRow(func(n int) bool { return n == len(header) })
+		if !ok {
+			break
+		}
+
+		errs := len(c.report.Errors)
+		ID, idCol := c.value(row, FieldID)
+		ID = spec.NormalizeID.normalize(ID)
+		name, nameCol := c.value(row, FieldName)
+		weight := 1
+		var attributes map[string]string
+
+		if strings.TrimSpace(ID) == "" {
+			c.errorf(idCol, "ID is blank")
+		} else if first, ok := lines[ID]; ok {
+			c.errorf(idCol, "duplicate ID %v, first defined at line %v", ID, first)
+		}
+
+		if strings.TrimSpace(name) == "" {
+			c.errorf(nameCol, "name is blank")
+		}
+
+		if v, j := c.value(row, FieldWeight); j >= 0 {
+			v = strings.Trim(v, " ")
+			if v != "" {
+				w, err := strconv.Atoi(v)
+				if err != nil || w < 1 {
+					c.errorf(j, "weight %q is not a positive integer", v)
+				} else {
+					weight = w
+				}
+			}
+		}
+
+		for _, j := range attributeCols {
+			if attributes == nil {
+				attributes = make(map[string]string)
+			}
+			attributes[strings.Trim(header[j], " ")] = strings.Trim(row[j], " ")
+		}
+
+		if len(c.report.Errors) > errs {
+			continue
+		}
+
+		line, _ := c.reader.FieldPos(0)
+		lines[ID] = line
+		participants[ID] = Participant{ID, name, weight, attributes}
+	}
+
+	c.report.Imported = len(participants)
+	return participants, c.report, c.malformed
+}
+
+// parsePrizesCSV parses and validates the prizes CSV.
+// See LoadPrizesCSV for the format.
+// The CSV fields are merged into the existing prizes with the same nos.
+// It returns the valid prizes, the report and whether the CSV is malformed.
+func parsePrizesCSV(r io.Reader, spec ImportSpec, existing map[int]Prize) (map[int]Prize, ImportReport, bool) {
+	prizes := make(map[int]Prize)
+
+	c := newCSVImport(r, spec)
+	validLen := func(n int) bool { return n == len(c.header) }
+	if len(spec.Columns) > 0 {
+		if !c.readHeader(3) || !c.mapColumns(FieldNo, FieldName, FieldAmount) {
+			return prizes, c.report, true
+		}
+	} else {
+		// The columns are No, Name, Amount, Desc and the optional Alternates.
+		if !c.readHeader(4) {
+			return prizes, c.report, true
+		}
+		for j, f := range prizeFields {
+			c.columns[f] = j
+		}
+		validLen = func(n int) bool { return n == 4 || n == 5 }
+	}
+
+	// lines maps prize no to the line of the prize.
+	lines := make(map[int]int)
+	for {
+		row, ok := c.readRow(validLen)
+		if !ok {
+			break
+		}
+
+		errs := len(c.report.Errors)
+
+		v, j := c.value(row, FieldNo)
+		v = strings.Trim(v, " ")
+		no, err := strconv.Atoi(v)
+		if err != nil {
+			c.errorf(j, "prize no %q is not an integer", v)
+		} else if first, ok := lines[no]; ok {
+			c.errorf(j, "duplicate prize no %v, first defined at line %v", no, first)
+		}
+
+		name, j := c.value(row, FieldName)
+		if strings.TrimSpace(name) == "" {
+			c.warnf(j, "name is blank")
+		}
+
+		v, j = c.value(row, FieldAmount)
+		v = strings.Trim(v, " ")
+		amount, err := strconv.Atoi(v)
+		if err != nil || amount <= 0 {
+			c.errorf(j, "amount %q is not a positive integer", v)
+		}
+
+		desc, _ := c.value(row, FieldDesc)
+
+		// Blank alternates keep the setting of the existing prize.
+		alternates := existing[no].Alternates
+		if v, j := c.value(row, FieldAlternates); strings.Trim(v, " ") != "" {
+			v = strings.Trim(v, " ")
+			alternates, err = strconv.Atoi(v)
+			if err != nil || alternates < 0 {
+				c.errorf(j, "alternates %q is not a non-negative integer", v)
+			}
+		}
+
+		if len(c.report.Errors) > errs {
+			continue
+		}
+
+		line, _ := c.reader.FieldPos(0)
+		lines[no] = line
+		prize := existing[no]
+		prize.No = no
+		prize.Name = name
+		prize.Amount = amount
+		prize.Desc = desc
+		prize.Alternates = alternates
+		prizes[no] = prize
+	}
+
+	c.report.Imported = len(prizes)
+	return prizes, c.report, c.malformed
+}
+
+// importError returns the error of the report if it has errors, or has
+// warnings in strict mode.
+func importError(err error, report ImportReport, strict bool) error {
+	if len(report.Errors) > 0 || (strict && len(report.Warnings) > 0) {
+		return &ImportError{err, report}
+	}
+	return nil
+}
+
+// ValidateParticipantsCSV validates the participants CSV without loading it.
+// It returns an *ImportError with the report if the CSV has errors, or has
+// warnings in strict mode.
+func ValidateParticipantsCSV(r io.Reader, spec ImportSpec) (ImportReport, error) {
+	r, err := spec.reader(r, participantFields)
+	if err != nil {
+		return newImportReport(), err
+	}
+
+	_, report, _ := parseParticipantsCSV(r, spec)
+	return report, importError(ErrParticipantsCSV, report, spec.Strict)
+}
+
+// ValidatePrizesCSV validates the prizes CSV without loading it.
+// It returns an *ImportError with the report if the CSV has errors, or has
+// warnings in strict mode.
+func ValidatePrizesCSV(r io.Reader, spec ImportSpec) (ImportReport, error) {
+	r, err := spec.reader(r, prizeFields)
+	if err != nil {
+		return newImportReport(), err
+	}
+
+	_, report, _ := parsePrizesCSV(r, spec, nil)
+	return report, importError(ErrPrizesCSV, report, spec.Strict)
+}
+
+// ImportParticipantsCSV imports participants from the CSV and returns the
+// validation report. See LoadParticipantsCSV for the format.
+// Rows with errors are skipped unless spec.Strict is set, which refuses to
+// load the CSV if there're any errors or warnings.
+// It returns an *ImportError with the report if nothing is loaded, or
+// ErrImportSpec if the spec is incorrect.
+func (l *Lottery) ImportParticipantsCSV(r io.Reader, spec ImportSpec) (ImportReport, error) {
+	return l.importParticipantsCSV(r, spec, true)
+}
+
+// importParticipantsCSV imports participants from the CSV.
+// If skipInvalid is false, nothing is loaded if there're errors.
+func (l *Lottery) importParticipantsCSV(r io.Reader, spec ImportSpec, skipInvalid bool) (ImportReport, error) {
+	r, err := spec.reader(r, participantFields)
+	if err != nil {
+		return newImportReport(), err
+	}
+
+	participants, report, malformed := parseParticipantsCSV(r, spec)
+	if err := importError(ErrParticipantsCSV, report, spec.Strict); err != nil {
+		if malformed || spec.Strict || !skipInvalid {
+			return report, err
+		}
+	}
+
+	// Keep the ID normalization to normalize the IDs of blacklists and
+	// history.
+	var normalizeID *IDNormalization
+	if spec.NormalizeID != (IDNormalization{}) {
+		normalizeID = &spec.NormalizeID
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if err := l.record(Event{Type: EventLoadParticipants, Participants: participants, NormalizeID: normalizeID, Meta: l.meta}); err != nil {
+		return report, err
+	}
+
+	l.participants = participants
+	l.normalizeID = normalizeID
+	return report, nil
+}
+
+// ImportParticipantsCSVFile imports participants from the CSV file.
+// See ImportParticipantsCSV for more information.
+func (l *Lottery) ImportParticipantsCSVFile(file string, spec ImportSpec) (ImportReport, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return newImportReport(), err
+	}
+	defer f.Close()
+
+	return l.ImportParticipantsCSV(f, spec)
+}
+
+// ImportPrizesCSV imports prizes from the CSV and returns the validation
+// report. See LoadPrizesCSV for the format.
+// Rows with errors are skipped unless spec.Strict is set, which refuses to
+// load the CSV if there're any errors or warnings.
+// It returns an *ImportError with the report if nothing is loaded, or
+// ErrImportSpec if the spec is incorrect.
+func (l *Lottery) ImportPrizesCSV(r io.Reader, spec ImportSpec) (ImportReport, error) {
+	return l.importPrizesCSV(r, spec, true)
+}
+
+// importPrizesCSV imports prizes from the CSV.
+// If skipInvalid is false, nothing is loaded if there're errors.
+func (l *Lottery) importPrizesCSV(r io.Reader, spec ImportSpec, skipInvalid bool) (ImportReport, error) {
+	r, err := spec.reader(r, prizeFields)
+	if err != nil {
+		return newImportReport(), err
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	prizes, report, malformed := parsePrizesCSV(r, spec, l.prizes)
+	if err := importError(ErrPrizesCSV, report, spec.Strict); err != nil {
+		if malformed || spec.Strict || !skipInvalid {
+			return report, err
+		}
+	}
+
+	if err := l.record(Event{Type: EventLoadPrizes, Prizes: prizes, Meta: l.meta}); err != nil {
+		return report, err
+	}
+
+	l.prizes = prizes
+	return report, nil
+}
+
+// ImportPrizesCSVFile imports prizes from the CSV file.
+// See ImportPrizesCSV for more information.
+func (l *Lottery) ImportPrizesCSVFile(file string, spec ImportSpec) (ImportReport, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return newImportReport(), err
+	}
+	defer f.Close()
+
+	return l.ImportPrizesCSV(f, spec)
+}
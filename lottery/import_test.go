@@ -0,0 +1,330 @@
+package lottery_test
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/northbright/lottery-go/lottery"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+const invalidParticipantsCSV = `ID,Name,Weight,Team
+1,Alice,,A
+2,,1,B
+1,Alice again,,A
+3,Carol,x,C
+4,Dave,A
+ ,Eve,,E
+5,Frank,2,F
+`
+
+func TestImportParticipantsCSV(t *testing.T) {
+	want := []lottery.ImportIssue{
+		{3, 2, "Name", "name is blank"},
+		{4, 1, "ID", "duplicate ID 1, first defined at line 2"},
+		{5, 3, "Weight", `weight "x" is not a positive integer`},
+		{6, 0, "", "row has 3 fields, header has 4"},
+		{7, 1, "ID", "ID is blank"},
+	}
+
+	report, err := lottery.ValidateParticipantsCSV(strings.NewReader(invalidParticipantsCSV), lottery.ImportSpec{})
+	if !errors.Is(err, lottery.ErrParticipantsCSV) {
+		t.Errorf("ValidateParticipantsCSV() = %v, want %v", err, lottery.ErrParticipantsCSV)
+	}
+	if !reflect.DeepEqual(report.Errors, want) {
+		t.Errorf("errors = %v, want %v", report.Errors, want)
+	}
+	if report.Rows != 7 || report.Imported != 2 {
+		t.Errorf("rows = %v, imported = %v, want 7, 2", report.Rows, report.Imported)
+	}
+
+	// Load fails with the report.
+	l := lottery.New("import")
+	err = l.LoadParticipantsCSV(strings.NewReader(invalidParticipantsCSV))
+	importErr, ok := err.(*lottery.ImportError)
+	if !ok || importErr.Err != lottery.ErrParticipantsCSV || len(importErr.Report.Errors) != len(want) {
+		t.Fatalf("LoadParticipantsCSV() = %v, want *ImportError", err)
+	}
+	if !strings.Contains(err.Error(), "line 3, column 2(Name): name is blank") {
+		t.Errorf("error message = %v", err)
+	}
+	if len(l.Participants()) != 0 {
+		t.Errorf("participants loaded: %v", l.Participants())
+	}
+
+	// Rows with errors are skipped.
+	if _, err := l.ImportParticipantsCSV(strings.NewReader(invalidParticipantsCSV), lottery.ImportSpec{}); err != nil {
+		t.Fatalf("ImportParticipantsCSV() error: %v", err)
+	}
+	participants := l.Participants()
+	if len(participants) != 2 || l.Participants()[0].Name == "Alice again" {
+		t.Errorf("participants = %v, want Alice and Frank", participants)
+	}
+
+	// Strict mode refuses to load.
+	strict := lottery.New("strict")
+	if _, err := strict.ImportParticipantsCSV(strings.NewReader(invalidParticipantsCSV), lottery.ImportSpec{Strict: true}); !errors.Is(err, lottery.ErrParticipantsCSV) {
+		t.Errorf("ImportParticipantsCSV() strict = %v, want %v", err, lottery.ErrParticipantsCSV)
+	}
+	if len(strict.Participants()) != 0 {
+		t.Errorf("participants loaded in strict mode: %v", strict.Participants())
+	}
+
+	// Warnings.
+	csv := "ID,Name,Team,Team\n1,Alice,A,B\n"
+	report, err = strict.ImportParticipantsCSV(strings.NewReader(csv), lottery.ImportSpec{Strict: true})
+	if err == nil || len(report.Warnings) != 1 || report.Warnings[0].Column != 4 {
+		t.Errorf("ImportParticipantsCSV() strict with warnings = %v, %v", report, err)
+	}
+	if _, err := strict.ImportParticipantsCSV(strings.NewReader(csv), lottery.ImportSpec{}); err != nil {
+		t.Errorf("ImportParticipantsCSV() with warnings error: %v", err)
+	}
+
+	// Columns with blank header are skipped.
+	csv = "ID,Name, ,Team\n1,Alice,x,A\n"
+	report, err = strict.ImportParticipantsCSV(strings.NewReader(csv), lottery.ImportSpec{Strict: true})
+	if !errors.Is(err, lottery.ErrParticipantsCSV) || len(report.Warnings) != 1 || report.Warnings[0].Column != 3 {
+		t.Errorf("ImportParticipantsCSV() strict with blank header = %v, %v", report, err)
+	}
+	if _, err := strict.ImportParticipantsCSV(strings.NewReader(csv), lottery.ImportSpec{}); err != nil {
+		t.Errorf("ImportParticipantsCSV() with blank header error: %v", err)
+	}
+	if p := strict.Participants(); len(p) != 1 || !reflect.DeepEqual(p[0].Attributes, map[string]string{"Team": "A"}) {
+		t.Errorf("participants = %v, want attributes of Team only", p)
+	}
+
+	// Malformed CSV.
+	report, err = l.ImportParticipantsCSV(strings.NewReader("ID,Name\n1,\"Alice\n"), lottery.ImportSpec{})
+	if err == nil || len(report.Errors) != 1 || report.Errors[0].Line != 2 {
+		t.Errorf("ImportParticipantsCSV() malformed = %v, %v", report, err)
+	}
+}
+
+func TestImportPrizesCSV(t *testing.T) {
+	csv := `No,Name,Amount,Desc,Alternates
+1,1st prize,1,iPhone,
+2,2nd prize,0,Macbook,
+1,1st prize again,1,iPad,
+3,,5,Speaker,x
+4,,5,Speaker,1
+`
+	want := []lottery.ImportIssue{
+		{3, 3, "Amount", `amount "0" is not a positive integer`},
+		{4, 1, "No", "duplicate prize no 1, first defined at line 2"},
+		{5, 5, "Alternates", `alternates "x" is not a non-negative integer`},
+	}
+
+	l := lottery.New("prizes")
+	err := l.LoadPrizesCSV(strings.NewReader(csv))
+	if !errors.Is(err, lottery.ErrPrizesCSV) {
+		t.Fatalf("LoadPrizesCSV() = %v, want %v", err, lottery.ErrPrizesCSV)
+	}
+	report := err.(*lottery.ImportError).Report
+	if !reflect.DeepEqual(report.Errors, want) {
+		t.Errorf("errors = %v, want %v", report.Errors, want)
+	}
+	if len(report.Warnings) != 2 {
+		t.Errorf("warnings = %v, want 2 blank names", report.Warnings)
+	}
+
+	report, err = l.ImportPrizesCSV(strings.NewReader(csv), lottery.ImportSpec{})
+	if err != nil {
+		t.Fatalf("ImportPrizesCSV() error: %v", err)
+	}
+	if report.Imported != 2 || len(l.Prizes(false)) != 2 || l.Prize(1).Desc != "iPhone" {
+		t.Errorf("prizes = %v, want prize 1 and 4", l.Prizes(false))
+	}
+}
+
+func TestLoadPrizesCSVKeepsSettings(t *testing.T) {
+	l := lottery.New("prizes")
+	l.SetPrize(1, "1st prize", 1, "")
+	l.SetPrize(2, "2nd prize", 2, "")
+	rules := []lottery.Rule{{Attribute: "Office", Op: lottery.RuleIn, Values: []string{"Shanghai"}}}
+	if err := l.SetPrizeRules(1, rules); err != nil {
+		t.Fatalf("SetPrizeRules() error: %v", err)
+	}
+	if err := l.SetPrizeAlternates(1, 2); err != nil {
+		t.Fatalf("SetPrizeAlternates() error: %v", err)
+	}
+	if err := l.SetPrizeRepeatWinners(1, lottery.RepeatAny, nil); err != nil {
+		t.Fatalf("SetPrizeRepeatWinners() error: %v", err)
+	}
+
+	csv := `No,Name,Amount,Desc,Alternates
+1,Grand prize,3,iPhone,
+3,3rd prize,5,Speaker,1
+`
+	if err := l.LoadPrizesCSV(strings.NewReader(csv)); err != nil {
+		t.Fatalf("LoadPrizesCSV() error: %v", err)
+	}
+
+	prize := l.Prize(1)
+	if prize.Name != "Grand prize" || prize.Amount != 3 || prize.Desc != "iPhone" {
+		t.Errorf("prize 1 = %+v, want CSV fields", prize)
+	}
+	if !reflect.DeepEqual(prize.Rules, rules) || prize.Alternates != 2 || prize.RepeatWinners != lottery.RepeatAny {
+		t.Errorf("prize 1 = %+v, want settings kept", prize)
+	}
+	if len(l.Prizes(false)) != 2 || l.Prize(3).Alternates != 1 {
+		t.Errorf("prizes = %v, want prize 1 and 3", l.Prizes(false))
+	}
+}
+
+func TestImportSpec(t *testing.T) {
+	csv := "备注;姓名;部门;工号\n" +
+		"new; 张三 ;研发;ａ1\n" +
+		";李四;市场;A1\n" +
+		";王五;市场;007\n"
+	gbk, err := simplifiedchinese.GBK.NewEncoder().String(csv)
+	if err != nil {
+		t.Fatalf("encode GBK error: %v", err)
+	}
+
+	spec := lottery.ImportSpec{
+		Columns:   map[string]string{"工号": "id", "姓名": "name", "备注": "-"},
+		Delimiter: ";",
+		Encoding:  lottery.EncodingGBK,
+		Trim:      true,
+		NormalizeID: lottery.IDNormalization{
+			Case:             lottery.CaseUpper,
+			HalfWidth:        true,
+			TrimLeadingZeros: true,
+		},
+	}
+
+	l := lottery.New("spec")
+	report, err := l.ImportParticipantsCSV(strings.NewReader(gbk), spec)
+	if err != nil {
+		t.Fatalf("ImportParticipantsCSV() error: %v", err)
+	}
+
+	// "ａ1" and "A1" are the same ID.
+	want := []lottery.ImportIssue{{3, 4, "工号", "duplicate ID A1, first defined at line 2"}}
+	if !reflect.DeepEqual(report.Errors, want) {
+		t.Errorf("errors = %v, want %v", report.Errors, want)
+	}
+
+	participants := map[string]lottery.Participant{}
+	for _, p := range l.Participants() {
+		participants[p.ID] = p
+	}
+	wantParticipants := map[string]lottery.Participant{
+		"A1": {ID: "A1", Name: "张三", Weight: 1, Attributes: map[string]string{"部门": "研发"}},
+		"7":  {ID: "7", Name: "王五", Weight: 1, Attributes: map[string]string{"部门": "市场"}},
+	}
+	if !reflect.DeepEqual(participants, wantParticipants) {
+		t.Errorf("participants = %v, want %v", participants, wantParticipants)
+	}
+
+	// UTF-8 with BOM.
+	bom := "\xEF\xBB\xBFName,ID\nAlice,1\n"
+	spec = lottery.ImportSpec{Columns: map[string]string{"id": "id", "name": "name"}}
+	if _, err := l.ImportParticipantsCSV(strings.NewReader(bom), spec); err != nil {
+		t.Fatalf("ImportParticipantsCSV() with BOM error: %v", err)
+	}
+	if p := l.Participants(); len(p) != 1 || p[0].ID != "1" || p[0].Name != "Alice" {
+		t.Errorf("participants = %v, want Alice", p)
+	}
+
+	// The required column is missing.
+	spec = lottery.ImportSpec{Columns: map[string]string{"工号": "id"}}
+	if _, err := l.ImportParticipantsCSV(strings.NewReader("工号,名字\n1,Alice\n"), spec); !errors.Is(err, lottery.ErrParticipantsCSV) {
+		t.Errorf("ImportParticipantsCSV() without name column = %v, want %v", err, lottery.ErrParticipantsCSV)
+	}
+
+	// Incorrect specs.
+	for _, spec := range []lottery.ImportSpec{
+		{Columns: map[string]string{"工号": "no"}},
+		{Columns: map[string]string{"工号": "id", "编号": "id"}},
+		{Delimiter: ";;"},
+		{Encoding: "big5"},
+		{NormalizeID: lottery.IDNormalization{Case: "title"}},
+	} {
+		if _, err := l.ImportParticipantsCSV(strings.NewReader(csv), spec); err != lottery.ErrImportSpec {
+			t.Errorf("ImportParticipantsCSV() with spec %+v = %v, want %v", spec, err, lottery.ErrImportSpec)
+		}
+	}
+}
+
+func TestImportSpecNormalizedIDs(t *testing.T) {
+	spec := lottery.ImportSpec{NormalizeID: lottery.IDNormalization{TrimLeadingZeros: true}}
+
+	l := lottery.New("normalize")
+	if _, err := l.ImportParticipantsCSV(strings.NewReader("ID,Name\n00123,Alice\n2,Bob\n3,Carol\n"), spec); err != nil {
+		t.Fatalf("ImportParticipantsCSV() error: %v", err)
+	}
+	l.SetPrize(1, "1st prize", 1, "")
+
+	// IDs of the blacklist and the history are normalized as well.
+	if err := l.SetBlacklist(2, []string{"00123"}); err != nil {
+		t.Fatalf("SetBlacklist() error: %v", err)
+	}
+
+	h := lottery.NewHistory()
+	data := lottery.SaveData{
+		Prizes:  map[int]lottery.Prize{1: {No: 1, Name: "1st prize", Amount: 1}},
+		Winners: map[int][]lottery.Winner{1: {{Participant: lottery.Participant{ID: "0002", Name: "Bob"}}}},
+	}
+	if err := h.ImportSaveData("2025", data); err != nil {
+		t.Fatalf("ImportSaveData() error: %v", err)
+	}
+	l.SetHistory(h)
+
+	rules := []lottery.Rule{{Op: lottery.RuleHistoryExclude}}
+	if err := l.SetPrizeRules(1, rules); err != nil {
+		t.Fatalf("SetPrizeRules() error: %v", err)
+	}
+
+	check := func(l *lottery.Lottery) {
+		t.Helper()
+		if available := l.AvailableParticipants(1); len(available) != 1 || available[0].ID != "3" {
+			t.Errorf("AvailableParticipants() = %v, want Carol only", available)
+		}
+	}
+	check(l)
+
+	// The normalization is saved with the participants.
+	buf := &bytes.Buffer{}
+	if err := l.Save(buf); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+
+	loaded := lottery.New("normalize")
+	if err := loaded.Load(buf); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	loaded.SetHistory(h)
+	check(loaded)
+}
+
+func TestImportPrizesSpec(t *testing.T) {
+	csv := "奖项名称\t数量\t编号\n一等奖\t1\t1\n二等奖\t2\t2\n"
+	gb18030, err := simplifiedchinese.GB18030.NewEncoder().String(csv)
+	if err != nil {
+		t.Fatalf("encode GB18030 error: %v", err)
+	}
+
+	spec := lottery.ImportSpec{
+		Columns:   map[string]string{"编号": "no", "奖项名称": "name", "数量": "amount"},
+		Delimiter: "\t",
+		Encoding:  lottery.EncodingGB18030,
+		Strict:    true,
+	}
+
+	l := lottery.New("spec")
+	if _, err := l.ImportPrizesCSV(strings.NewReader(gb18030), spec); err != nil {
+		t.Fatalf("ImportPrizesCSV() error: %v", err)
+	}
+
+	want := []lottery.Prize{
+		{No: 1, Name: "一等奖", Amount: 1},
+		{No: 2, Name: "二等奖", Amount: 2},
+	}
+	if prizes := l.Prizes(false); !reflect.DeepEqual(prizes, want) {
+		t.Errorf("prizes = %v, want %v", prizes, want)
+	}
+}
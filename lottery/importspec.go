@@ -0,0 +1,206 @@
+package lottery
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/width"
+)
+
+// ImportSpec specifies how to import participants or prizes from CSV.
+// The zero value imports comma-separated UTF-8 CSV with the columns in the
+// default order. See LoadParticipantsCSV and LoadPrizesCSV.
+type ImportSpec struct {
+	// Strict refuses to load anything if there're errors or warnings.
+	// Otherwise rows with errors are skipped and rows with warnings are
+	// loaded.
+	Strict bool `json:"strict,omitempty"`
+	// Columns maps the header names to the fields(optional).
+	// Header names are case-insensitive.
+	// Participant fields: "id", "name" and "weight". Other columns of the
+	// participants are loaded as attributes named by the header.
+	// Prize fields: "no", "name", "amount", "desc" and "alternates".
+	// Map a header to "-" to ignore the column.
+	// e.g. {"工号": "id", "姓名": "name", "备注": "-"}.
+	// If it's set, the columns are found by the header instead of the order,
+	// and the columns of "id", "name" of participants and "no", "name",
+	// "amount" of prizes are required.
+	Columns map[string]string `json:"columns,omitempty"`
+	// Delimiter is the field delimiter, e.g. ";" or "\t". Default is ",".
+	Delimiter string `json:"delimiter,omitempty"`
+	// Encoding is the encoding of the CSV: "utf-8"(default), "utf-8-bom",
+	// "gbk" or "gb18030". The BOM of UTF-8 CSV is skipped.
+	Encoding string `json:"encoding,omitempty"`
+	// Trim trims the leading and trailing spaces of all fields.
+	Trim bool `json:"trim,omitempty"`
+	// NormalizeID normalizes the IDs of participants.
+	// The lottery keeps it to normalize the IDs of blacklists and history,
+	// so they still match the participants.
+	NormalizeID IDNormalization `json:"normalize_id,omitempty"`
+}
+
+// IDNormalization normalizes the IDs of participants before they're
+// validated, so the same ID written differently is found as duplicate.
+type IDNormalization struct {
+	// Case converts the IDs to "upper" or "lower" case(optional).
+	Case string `json:"case,omitempty"`
+	// HalfWidth converts full-width letters and digits to half-width,
+	// e.g. "ＡＢ１２" -> "AB12".
+	HalfWidth bool `json:"half_width,omitempty"`
+	// TrimLeadingZeros removes the leading zeros, e.g. "007" -> "7".
+	TrimLeadingZeros bool `json:"trim_leading_zeros,omitempty"`
+}
+
+const (
+	EncodingUTF8    = "utf-8"
+	EncodingUTF8BOM = "utf-8-bom"
+	EncodingGBK     = "gbk"
+	EncodingGB18030 = "gb18030"
+
+	// Fields of participants and prizes in the CSV.
+	FieldID         = "id"
+	FieldName       = "name"
+	FieldWeight     = "weight"
+	FieldNo         = "no"
+	FieldAmount     = "amount"
+	FieldDesc       = "desc"
+	FieldAlternates = "alternates"
+	// FieldIgnore ignores the column.
+	FieldIgnore = "-"
+
+	CaseUpper = "upper"
+	CaseLower = "lower"
+)
+
+var (
+	ErrImportSpec = fmt.Errorf("incorrect import spec")
+
+	participantFields = []string{FieldID, FieldName, FieldWeight}
+	prizeFields       = []string{FieldNo, FieldName, FieldAmount, FieldDesc, FieldAlternates}
+
+	utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+)
+
+// check checks the spec with the fields of the CSV.
+func (spec ImportSpec) check(fields []string) error {
+	mapped := make(map[string]bool)
+	for _, f := range spec.Columns {
+		if f == FieldIgnore {
+			continue
+		}
+
+		valid := false
+		for _, field := range fields {
+			if f == field {
+				valid = true
+			}
+		}
+
+		if !valid || mapped[f] {
+			return ErrImportSpec
+		}
+		mapped[f] = true
+	}
+
+	if spec.Delimiter != "" {
+		r, size := utf8.DecodeRuneInString(spec.Delimiter)
+		if size != len(spec.Delimiter) || r == utf8.RuneError || r == '"' || r == '\r' || r == '\n' {
+			return ErrImportSpec
+		}
+	}
+
+	switch strings.ToLower(spec.Encoding) {
+	case "", EncodingUTF8, EncodingUTF8BOM, EncodingGBK, EncodingGB18030:
+	default:
+		return ErrImportSpec
+	}
+
+	switch spec.NormalizeID.Case {
+	case "", CaseUpper, CaseLower:
+	default:
+		return ErrImportSpec
+	}
+
+	return nil
+}
+
+// reader checks the spec and returns the reader which decodes the CSV to
+// UTF-8.
+func (spec ImportSpec) reader(r io.Reader, fields []string) (io.Reader, error) {
+	if err := spec.check(fields); err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(spec.Encoding) {
+	case EncodingGBK:
+		return transform.NewReader(r, simplifiedchinese.GBK.NewDecoder()), nil
+	case EncodingGB18030:
+		return transform.NewReader(r, simplifiedchinese.GB18030.NewDecoder()), nil
+	default:
+		// Skip the BOM written by Excel.
+		br := bufio.NewReader(r)
+		if b, err := br.Peek(len(utf8BOM)); err == nil && bytes.Equal(b, utf8BOM) {
+			br.Discard(len(utf8BOM))
+		}
+		return br, nil
+	}
+}
+
+// delimiter returns the field delimiter.
+func (spec ImportSpec) delimiter() rune {
+	if spec.Delimiter == "" {
+		return ','
+	}
+
+	r, _ := utf8.DecodeRuneInString(spec.Delimiter)
+	return r
+}
+
+// field returns the field of the header by the column mapping.
+func (spec ImportSpec) field(header string) (string, bool) {
+	for h, f := range spec.Columns {
+		if strings.EqualFold(strings.TrimSpace(h), strings.TrimSpace(header)) {
+			return f, true
+		}
+	}
+	return "", false
+}
+
+// normalize returns the normalized ID.
+func (n IDNormalization) normalize(ID string) string {
+	if n.HalfWidth {
+		ID = width.Narrow.String(ID)
+	}
+
+	switch n.Case {
+	case CaseUpper:
+		ID = strings.ToUpper(ID)
+	case CaseLower:
+		ID = strings.ToLower(ID)
+	}
+
+	if n.TrimLeadingZeros {
+		trimmed := strings.TrimLeft(ID, "0")
+		if trimmed == "" && ID != "" {
+			trimmed = "0"
+		}
+		ID = trimmed
+	}
+
+	return ID
+}
+
+// normalize normalizes the ID by the ID normalization of the imported
+// participants.
+func (l *Lottery) normalize(ID string) string {
+	if l.normalizeID == nil {
+		return ID
+	}
+	return l.normalizeID.normalize(ID)
+}
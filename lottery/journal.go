@@ -0,0 +1,628 @@
+package lottery
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Event is a mutating operation recorded in the journal.
+// Events of draw-related operations carry the state of the prize after
+// the operation, so replaying them does not depend on the RNG.
+type Event struct {
+	// Seq is the sequence number of the event in the journal.
+	Seq  int    `json:"seq"`
+	Type string `json:"type"`
+	Time string `json:"time"`
+
+	// Arguments of the operation.
+	PrizeNo   int      `json:"prize_no,omitempty"`
+	Amount    int      `json:"amount,omitempty"`
+	Attribute string   `json:"attribute,omitempty"`
+	IDs       []string `json:"ids,omitempty"`
+	Reason    string   `json:"reason,omitempty"`
+	Policy    string   `json:"policy,omitempty"`
+	// Meta is the actor and reason metadata of the operation.
+	Meta *Meta `json:"meta,omitempty"`
+
+	// Settings after the operation.
+	Prize        *Prize                 `json:"prize,omitempty"`
+	Prizes       map[int]Prize          `json:"prizes,omitempty"`
+	Blacklist    *Blacklist             `json:"blacklist,omitempty"`
+	Blacklists   map[int]Blacklist      `json:"blacklists,omitempty"`
+	Participants map[string]Participant `json:"participants,omitempty"`
+	NormalizeID  *IDNormalization       `json:"normalize_id,omitempty"`
+	GroupCap     *GroupCap              `json:"group_cap,omitempty"`
+
+	// State is the state of the prize after a draw-related operation.
+	State *PrizeState `json:"state,omitempty"`
+	// Round is the last round number of the prize after a draw-related
+	// operation.
+	Round int `json:"round,omitempty"`
+	// Data is the saved data of the whole state of a snapshot event.
+	// It's decoded by DecodeSaveData, so snapshots of old versions are
+	// migrated.
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// PrizeState is the state of the winners of a prize.
+type PrizeState struct {
+	Winners     []Winner          `json:"winners"`
+	Alternates  []Participant     `json:"alternates,omitempty"`
+	Claims      map[string]string `json:"claims,omitempty"`
+	Revocations []Revocation      `json:"revocations,omitempty"`
+	DrawRecords []DrawRecord      `json:"draw_records,omitempty"`
+}
+
+// Event types.
+const (
+	// EventSnapshot is the whole state of the lottery.
+	// A compacted journal starts with a snapshot.
+	EventSnapshot = "snapshot"
+	// EventLoad and EventRestoreBackup are the whole state of the lottery
+	// loaded from the saved data or restored from a backup. Unlike
+	// EventSnapshot, they're operations in the audit log.
+	EventLoad          = "load"
+	EventRestoreBackup = "restore_backup"
+
+	EventSetPrize              = "set_prize"
+	EventSetPrizeRules         = "set_prize_rules"
+	EventSetPrizeGroupCap      = "set_prize_group_cap"
+	EventSetPrizeRepeatWinners = "set_prize_repeat_winners"
+	EventSetPrizeAlternates    = "set_prize_alternates"
+	EventLoadPrizes            = "load_prizes"
+	EventLoadPrizeRules        = "load_prize_rules"
+	EventSetBlacklist          = "set_blacklist"
+	EventLoadBlacklists        = "load_blacklists"
+	EventLoadParticipants      = "load_participants"
+	EventSetGroupCap           = "set_group_cap"
+	EventSetRevokePolicy       = "set_revoke_policy"
+	EventDraw                  = "draw"
+	EventDrawBatch             = "draw_batch"
+	EventDrawStratified        = "draw_stratified"
+	EventRevoke                = "revoke"
+	EventRedraw                = "redraw"
+	EventReplace               = "replace"
+	EventPromoteAlternate      = "promote_alternate"
+	EventConfirm               = "confirm"
+	EventForfeit               = "forfeit"
+	EventDecline               = "decline"
+	EventClaimTimeout          = "claim_timeout"
+	EventClearWinners          = "clear_winners"
+	EventClearAllWinners       = "clear_all_winners"
+	EventUndo                  = "undo"
+	EventRedo                  = "redo"
+	EventFinalize              = "finalize"
+)
+
+var (
+	ErrJournalEvent = fmt.Errorf("incorrect journal event")
+	ErrNoJournal    = fmt.Errorf("journal is not opened")
+)
+
+// JournalFile returns the default journal file of the lottery in the
+// workspace.
+func (l *Lottery) JournalFile() string {
+	return l.workspace.JournalFile(l.name)
+}
+
+// OpenJournal opens the append-only journal file.
+// It rebuilds the state by replaying the existing events of the file,
+// then appends an event for each following mutating operation.
+// An incomplete event at the end of the file(e.g. written when crashed)
+// is discarded.
+// If the file is empty, the current state is recorded as a snapshot.
+func (l *Lottery) OpenJournal(file string) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(file, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	if info.Size() == 0 {
+		l.closeJournal()
+		l.journal = f
+		l.journalFile = file
+		l.journalSeq = 0
+		return l.record(Event{Type: EventSnapshot})
+	}
+
+	offset, err := l.replay(f)
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	// Discard the incomplete event.
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		f.Close()
+		return err
+	}
+
+	rest, err := io.ReadAll(f)
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	if len(bytes.TrimSpace(rest)) != 0 {
+		if err := f.Truncate(offset); err != nil {
+			f.Close()
+			return err
+		}
+
+		if _, err := f.Seek(offset, io.SeekStart); err != nil {
+			f.Close()
+			return err
+		}
+
+		if _, err := f.Write([]byte("\n")); err != nil {
+			f.Close()
+			return err
+		}
+	}
+
+	l.closeJournal()
+	l.journal = f
+	l.journalFile = file
+	return nil
+}
+
+// CloseJournal closes the journal. Following operations are not recorded.
+func (l *Lottery) CloseJournal() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return l.closeJournal()
+}
+
+func (l *Lottery) closeJournal() error {
+	if l.journal == nil {
+		return nil
+	}
+
+	err := l.journal.Close()
+	l.journal = nil
+	l.journalFile = ""
+	return err
+}
+
+// Replay rebuilds the state of the lottery by replaying the events of the
+// journal. The audit log is kept if it has more entries than the one of the
+// journal. The replay is recorded as a load event.
+func (l *Lottery) Replay(r io.Reader) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	audit := l.audit
+	if _, err := l.replay(r); err != nil {
+		return err
+	}
+
+	if len(audit) > len(l.audit) {
+		l.audit = audit
+	}
+
+	return l.record(Event{Type: EventLoad, Meta: l.meta})
+}
+
+// replay rebuilds the state by replaying the events.
+// It returns the offset after the last complete event.
+func (l *Lottery) replay(r io.Reader) (int64, error) {
+	l.reset()
+
+	dec := json.NewDecoder(r)
+	offset := int64(0)
+
+	for {
+		e := Event{}
+		err := dec.Decode(&e)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return offset, err
+		}
+
+		if err := l.apply(e); err != nil {
+			return offset, err
+		}
+
+		l.journalSeq = e.Seq
+		offset = dec.InputOffset()
+	}
+
+	l.stopClaimTimers()
+	l.startClaimTimers()
+	return offset, nil
+}
+
+// reset clears the state of the lottery before replaying.
+func (l *Lottery) reset() {
+	l.stopClaimTimers()
+
+	l.prizes = make(map[int]Prize)
+	l.blacklists = make(map[int]Blacklist)
+	l.participants = make(map[string]Participant)
+	l.normalizeID = nil
+	l.winners = make(map[int][]Winner)
+	l.rounds = make(map[int]int)
+	l.drawRecords = make(map[int][]DrawRecord)
+	l.groupCap = nil
+	l.alternates = make(map[int][]Participant)
+	l.claims = make(map[int]map[string]string)
+	l.revocations = make(map[int][]Revocation)
+	l.revokePolicies = make(map[string]string)
+	l.finalized = false
+	l.audit = nil
+	l.clearUndo()
+	l.journalSeq = 0
+}
+
+// apply applies the event to the state of the lottery.
+func (l *Lottery) apply(e Event) error {
+	switch e.Type {
+	case EventSnapshot, EventLoad, EventRestoreBackup:
+		if e.Data == nil {
+			return ErrJournalEvent
+		}
+
+		// Migrate the snapshot saved by old versions.
+		data, err := DecodeSaveData(bytes.NewReader(e.Data))
+		if err != nil {
+			return err
+		}
+		if err := l.load(data); err != nil {
+			return err
+		}
+		if e.Type == EventSnapshot {
+			return nil
+		}
+
+	case EventSetPrize, EventSetPrizeRules, EventSetPrizeGroupCap,
+		EventSetPrizeRepeatWinners, EventSetPrizeAlternates:
+		if e.Prize == nil {
+			return ErrJournalEvent
+		}
+		l.prizes[e.Prize.No] = *e.Prize
+
+	case EventLoadPrizes:
+		l.prizes = e.Prizes
+		if l.prizes == nil {
+			l.prizes = make(map[int]Prize)
+		}
+
+	case EventLoadPrizeRules:
+		for no, prize := range e.Prizes {
+			l.prizes[no] = prize
+		}
+
+	case EventSetBlacklist:
+		if e.Blacklist == nil {
+			return ErrJournalEvent
+		}
+		l.blacklists[e.Blacklist.MinPrizeNo] = *e.Blacklist
+
+	case EventLoadBlacklists:
+		l.blacklists = e.Blacklists
+		if l.blacklists == nil {
+			l.blacklists = make(map[int]Blacklist)
+		}
+
+	case EventLoadParticipants:
+		l.participants = e.Participants
+		if l.participants == nil {
+			l.participants = make(map[string]Participant)
+		}
+		l.normalizeID = e.NormalizeID
+
+	case EventSetGroupCap:
+		l.groupCap = e.GroupCap
+
+	case EventSetRevokePolicy:
+		l.revokePolicies[e.Reason] = e.Policy
+
+	case EventDraw, EventDrawBatch, EventDrawStratified, EventRevoke,
+		EventRedraw, EventReplace, EventPromoteAlternate, EventConfirm,
+		EventForfeit, EventDecline, EventClaimTimeout, EventClearWinners,
+		EventUndo, EventRedo:
+		if e.State == nil {
+			return ErrJournalEvent
+		}
+		l.setPrizeState(e.PrizeNo, *e.State)
+		if e.Round > l.rounds[e.PrizeNo] {
+			l.rounds[e.PrizeNo] = e.Round
+		}
+
+	case EventClearAllWinners:
+		l.winners = make(map[int][]Winner)
+		l.alternates = make(map[int][]Participant)
+		l.claims = make(map[int]map[string]string)
+		l.revocations = make(map[int][]Revocation)
+		l.clearUndo()
+
+	case EventFinalize:
+		l.finalized = true
+		l.clearUndo()
+
+	default:
+		return ErrJournalEvent
+	}
+
+	l.appendAudit(e)
+	return nil
+}
+
+// prizeState returns a copy of the state of the prize.
+func (l *Lottery) prizeState(prizeNo int) PrizeState {
+	return copyPrizeState(PrizeState{
+		l.winners[prizeNo],
+		l.alternates[prizeNo],
+		l.claims[prizeNo],
+		l.revocations[prizeNo],
+		l.drawRecords[prizeNo],
+	})
+}
+
+// copyPrizeState returns a copy of the state which does not share
+// slices or maps with the original.
+func copyPrizeState(s PrizeState) PrizeState {
+	c := PrizeState{}
+
+	if s.Winners != nil {
+		c.Winners = append([]Winner{}, s.Winners...)
+	}
+
+	if s.Alternates != nil {
+		c.Alternates = append([]Participant{}, s.Alternates...)
+	}
+
+	if s.Claims != nil {
+		c.Claims = make(map[string]string)
+		for ID, status := range s.Claims {
+			c.Claims[ID] = status
+		}
+	}
+
+	if s.Revocations != nil {
+		c.Revocations = append([]Revocation{}, s.Revocations...)
+	}
+
+	if s.DrawRecords != nil {
+		c.DrawRecords = append([]DrawRecord{}, s.DrawRecords...)
+	}
+
+	return c
+}
+
+// setPrizeState replaces the state of the prize.
+// It does not start or stop the claim timers.
+func (l *Lottery) setPrizeState(prizeNo int, s PrizeState) {
+	if s.Winners == nil {
+		delete(l.winners, prizeNo)
+	} else {
+		l.winners[prizeNo] = s.Winners
+	}
+
+	if s.Alternates == nil {
+		delete(l.alternates, prizeNo)
+	} else {
+		l.alternates[prizeNo] = s.Alternates
+	}
+
+	if s.Claims == nil {
+		delete(l.claims, prizeNo)
+	} else {
+		l.claims[prizeNo] = s.Claims
+	}
+
+	if s.Revocations == nil {
+		delete(l.revocations, prizeNo)
+	} else {
+		l.revocations[prizeNo] = s.Revocations
+	}
+
+	if s.DrawRecords == nil {
+		delete(l.drawRecords, prizeNo)
+	} else {
+		l.drawRecords[prizeNo] = s.DrawRecords
+	}
+}
+
+// record appends the event of an operation to the journal if the journal is
+// opened, then to the audit log.
+// Operations record the event before they change the state, or roll back
+// the changes if it returns an error, so the state never differs from the
+// journal.
+// A snapshot, load or restore event without data records the current state.
+func (l *Lottery) record(e Event) error {
+	e.Time = time.Now().Format("2006-01-02 15:04:05")
+
+	if l.journal != nil {
+		if (e.Type == EventSnapshot || e.Type == EventLoad || e.Type == EventRestoreBackup) && e.Data == nil {
+			data, err := l.saveData()
+			if err != nil {
+				return err
+			}
+			if e.Data, err = json.Marshal(&data); err != nil {
+				return err
+			}
+		}
+
+		e.Seq = l.journalSeq + 1
+
+		buf, err := json.Marshal(&e)
+		if err != nil {
+			return err
+		}
+
+		if _, err := l.journal.Write(append(buf, '\n')); err != nil {
+			return err
+		}
+
+		if err := l.journal.Sync(); err != nil {
+			return err
+		}
+
+		l.journalSeq = e.Seq
+	}
+
+	if e.Type != EventSnapshot {
+		l.appendAudit(e)
+	}
+
+	return nil
+}
+
+// prizeRollback is the state changed by a draw-related operation of a prize.
+// It's taken before the operation to roll back the changes if the event
+// can not be recorded.
+type prizeRollback struct {
+	prizeNo int
+	state   PrizeState
+	round   int
+	seed    []byte
+	undos   []undoEntry
+	redos   []undoEntry
+}
+
+// rollbackPoint returns the state of the prize to roll back to.
+func (l *Lottery) rollbackPoint(prizeNo int) prizeRollback {
+	return prizeRollback{
+		prizeNo,
+		l.prizeState(prizeNo),
+		l.rounds[prizeNo],
+		l.seeds[prizeNo],
+		l.undos,
+		l.redos,
+	}
+}
+
+// rollback restores the state of the prize before the operation.
+func (l *Lottery) rollback(r prizeRollback) {
+	l.restorePrizeState(r.prizeNo, r.state)
+
+	if r.round == 0 {
+		delete(l.rounds, r.prizeNo)
+	} else {
+		l.rounds[r.prizeNo] = r.round
+	}
+
+	if r.seed != nil {
+		l.seeds[r.prizeNo] = r.seed
+	}
+
+	l.undos = r.undos
+	l.redos = r.redos
+}
+
+// recordPrize records the draw-related event with the state of the prize.
+// If the event can not be recorded, the changes of the operation are rolled
+// back to r. Operations which can not be undone clear the undo history.
+func (l *Lottery) recordPrize(e Event, r prizeRollback) error {
+	if l.journal != nil {
+		s := l.prizeState(e.PrizeNo)
+		e.State = &s
+		e.Round = l.rounds[e.PrizeNo]
+	}
+
+	if err := l.record(e); err != nil {
+		l.rollback(r)
+		return err
+	}
+
+	if !undoable(e.Type) && e.Type != EventUndo && e.Type != EventRedo {
+		l.clearUndo()
+	}
+
+	return nil
+}
+
+// CompactJournal replaces the events of the journal with a snapshot of the
+// current state. The snapshot is written to a temporary file which is
+// renamed to the journal file after it's synced.
+func (l *Lottery) CompactJournal() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.journal == nil {
+		return ErrNoJournal
+	}
+
+	file := l.journalFile
+	tmp, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	data, err := l.saveData()
+	if err != nil {
+		return err
+	}
+
+	snapshot, err := json.Marshal(&data)
+	if err != nil {
+		return err
+	}
+
+	e := Event{
+		Seq:  l.journalSeq + 1,
+		Type: EventSnapshot,
+		Time: time.Now().Format("2006-01-02 15:04:05"),
+		Data: snapshot,
+	}
+
+	buf, err := json.Marshal(&e)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if _, err := tmp.Write(append(buf, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), file); err != nil {
+		return err
+	}
+
+	// Reopen the compacted journal to append.
+	l.journal.Close()
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		l.journal = nil
+		l.journalFile = ""
+		return err
+	}
+
+	l.journal = f
+	l.journalSeq = e.Seq
+	return nil
+}
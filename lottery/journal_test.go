@@ -0,0 +1,234 @@
+package lottery_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/northbright/lottery-go/lottery"
+)
+
+func openJournal(t *testing.T, file string) *lottery.Lottery {
+	l := lottery.New("journal")
+	if err := l.OpenJournal(file); err != nil {
+		t.Fatalf("OpenJournal() error: %v", err)
+	}
+	return l
+}
+
+// assertSameState checks if the lotteries have the same state.
+func assertSameState(t *testing.T, a, b *lottery.Lottery) {
+	t.Helper()
+
+	if !reflect.DeepEqual(a.Prizes(false), b.Prizes(false)) {
+		t.Errorf("prizes differ: %v, %v", a.Prizes(false), b.Prizes(false))
+	}
+	if !reflect.DeepEqual(a.Participants(), b.Participants()) {
+		t.Errorf("participants differ")
+	}
+	if !reflect.DeepEqual(a.AllWinners(), b.AllWinners()) {
+		t.Errorf("winners differ: %v, %v", a.AllWinners(), b.AllWinners())
+	}
+	for _, prize := range a.Prizes(false) {
+		if !reflect.DeepEqual(a.Revocations(prize.No), b.Revocations(prize.No)) {
+			t.Errorf("revocations of prize %v differ", prize.No)
+		}
+		for _, w := range a.Winners(prize.No) {
+			if a.ClaimStatus(prize.No, w.ID) != b.ClaimStatus(prize.No, w.ID) {
+				t.Errorf("claim status of %v differs", w.ID)
+			}
+		}
+	}
+}
+
+func TestJournalReplay(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "lottery.journal")
+
+	l := openJournal(t, file)
+	if err := l.LoadParticipantsCSVFile("settings/participants.example.csv"); err != nil {
+		t.Fatalf("LoadParticipantsCSVFile() error: %v", err)
+	}
+	if err := l.LoadPrizesCSVFile("settings/prizes.example.csv"); err != nil {
+		t.Fatalf("LoadPrizesCSVFile() error: %v", err)
+	}
+	l.SetBlacklist(3, []string{"1", "2"})
+	if err := l.SetRevokePolicy("late", lottery.RevokeExcludePrize); err != nil {
+		t.Fatalf("SetRevokePolicy() error: %v", err)
+	}
+
+	winners, err := l.Draw(5)
+	if err != nil {
+		t.Fatalf("Draw() error: %v", err)
+	}
+	if err := l.Revoke(5, []lottery.Participant{winners[0].Participant}, "late"); err != nil {
+		t.Fatalf("Revoke() error: %v", err)
+	}
+	if _, err := l.Redraw(5, 1); err != nil {
+		t.Fatalf("Redraw() error: %v", err)
+	}
+	if err := l.Confirm(5, winners[1].ID); err != nil {
+		t.Fatalf("Confirm() error: %v", err)
+	}
+	if _, err := l.Draw(4); err != nil {
+		t.Fatalf("Draw() error: %v", err)
+	}
+	l.ClearWinners(4)
+	if _, err := l.Undo(); err != nil {
+		t.Fatalf("Undo() error: %v", err)
+	}
+	if err := l.CloseJournal(); err != nil {
+		t.Fatalf("CloseJournal() error: %v", err)
+	}
+
+	// Rebuild the state from the journal.
+	replayed := openJournal(t, file)
+	defer replayed.CloseJournal()
+	assertSameState(t, l, replayed)
+
+	// Replay from a reader.
+	buf, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("ReadFile() error: %v", err)
+	}
+	fromReader := lottery.New("journal")
+	if err := fromReader.Replay(bytes.NewReader(buf)); err != nil {
+		t.Fatalf("Replay() error: %v", err)
+	}
+	assertSameState(t, l, fromReader)
+}
+
+func TestJournalCompact(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "lottery.journal")
+
+	l := openJournal(t, file)
+	defer l.CloseJournal()
+
+	if err := l.LoadParticipantsCSVFile("settings/participants.example.csv"); err != nil {
+		t.Fatalf("LoadParticipantsCSVFile() error: %v", err)
+	}
+	if err := l.LoadPrizesCSVFile("settings/prizes.example.csv"); err != nil {
+		t.Fatalf("LoadPrizesCSVFile() error: %v", err)
+	}
+	if _, err := l.Draw(5); err != nil {
+		t.Fatalf("Draw() error: %v", err)
+	}
+
+	if err := l.CompactJournal(); err != nil {
+		t.Fatalf("CompactJournal() error: %v", err)
+	}
+
+	buf, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("ReadFile() error: %v", err)
+	}
+	if n := strings.Count(string(buf), "\n"); n != 1 {
+		t.Errorf("compacted journal has %v events, want 1", n)
+	}
+
+	// Events after compaction are appended to the snapshot.
+	if _, err := l.Draw(4); err != nil {
+		t.Fatalf("Draw() error: %v", err)
+	}
+
+	replayed := openJournal(t, file)
+	defer replayed.CloseJournal()
+	assertSameState(t, l, replayed)
+}
+
+func TestJournalIncompleteEvent(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "lottery.journal")
+
+	l := openJournal(t, file)
+	l.SetPrize(1, "Grand", 1, "")
+	l.CloseJournal()
+
+	// Simulate a crash when writing an event.
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatalf("OpenFile() error: %v", err)
+	}
+	f.WriteString(`{"seq":2,"type":"set_pri`)
+	f.Close()
+
+	l = openJournal(t, file)
+	l.SetPrize(2, "Second", 2, "")
+	l.CloseJournal()
+
+	replayed := openJournal(t, file)
+	defer replayed.CloseJournal()
+	if prizes := replayed.Prizes(false); len(prizes) != 2 || prizes[1].Name != "Second" {
+		t.Errorf("prizes after replay: %v, want 2 prizes", prizes)
+	}
+}
+
+func TestJournalErrorRollback(t *testing.T) {
+	// Writes to /dev/full fail with ENOSPC.
+	if _, err := os.Stat("/dev/full"); err != nil {
+		t.Skip("/dev/full does not exist")
+	}
+
+	l := newSeededLottery(t, "rollback")
+	if err := l.SetPrizeAlternates(3, 1); err != nil {
+		t.Fatalf("SetPrizeAlternates() error: %v", err)
+	}
+	drawn, err := l.Draw(3)
+	if err != nil {
+		t.Fatalf("Draw() error: %v", err)
+	}
+	entries := len(l.AuditLog(lottery.AuditQuery{}))
+
+	if err := l.OpenJournal("/dev/full"); err == nil {
+		t.Fatalf("OpenJournal(/dev/full) should fail")
+	}
+
+	winners := l.Winners(3)
+	alternates := l.Alternates(3)
+	available := l.AvailableParticipants(2)
+
+	if err := l.SetPrize(3, "changed", 1, ""); err == nil {
+		t.Errorf("SetPrize() should fail")
+	}
+	if _, err := l.Draw(2); err == nil {
+		t.Errorf("Draw() should fail")
+	}
+	if _, err := l.PromoteAlternate(3, drawn[0].Participant, lottery.ReasonAbsent); err == nil {
+		t.Errorf("PromoteAlternate() should fail")
+	}
+	if err := l.Confirm(3, drawn[1].ID); err == nil {
+		t.Errorf("Confirm() should fail")
+	}
+	if err := l.Decline(3, drawn[2].ID); err == nil {
+		t.Errorf("Decline() should fail")
+	}
+	if err := l.ClearAllWinners(); err == nil {
+		t.Errorf("ClearAllWinners() should fail")
+	}
+
+	// Nothing is changed.
+	if l.Prize(3).Name == "changed" {
+		t.Errorf("prize 3 is changed")
+	}
+	if !reflect.DeepEqual(l.Winners(3), winners) || !reflect.DeepEqual(l.Alternates(3), alternates) {
+		t.Errorf("winners or alternates of prize 3 are changed")
+	}
+	if n := len(l.Winners(2)); n != 0 {
+		t.Errorf("got %v winners of prize 2, want 0", n)
+	}
+	if !reflect.DeepEqual(l.AvailableParticipants(2), available) {
+		t.Errorf("available participants of prize 2 are changed")
+	}
+	for _, w := range drawn {
+		if status := l.ClaimStatus(3, w.ID); status != lottery.ClaimPending {
+			t.Errorf("claim status of %v = %v, want %v", w.ID, status, lottery.ClaimPending)
+		}
+	}
+	if n := len(l.Revocations(3)); n != 0 {
+		t.Errorf("got %v revocations, want 0", n)
+	}
+	if n := len(l.AuditLog(lottery.AuditQuery{})); n != entries {
+		t.Errorf("got %v audit entries, want %v", n, entries)
+	}
+}
@@ -2,18 +2,13 @@ package lottery
 
 import (
 	"crypto/md5"
-	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
-	"log"
-	"math/rand"
 	"os"
-	"path"
 	"sort"
 	"strconv"
-	"strings"
 	"sync"
 	"time"
 )
@@ -21,6 +16,13 @@ import (
 type Participant struct {
 	ID   string `json:"id"`
 	Name string `json:"name"`
+	// Weight is the number of tickets of the participant.
+	// The chance of winning is proportional to the weight.
+	// 0 means the default weight: 1.
+	Weight int `json:"weight,omitempty"`
+	// Attributes are extra key / value attributes of the participant.
+	// e.g. department, office location, employment type and hire date.
+	Attributes map[string]string `json:"attributes,omitempty"`
 }
 
 type Prize struct {
@@ -28,6 +30,19 @@ type Prize struct {
 	Name   string `json:"name"`
 	Amount int    `json:"amount"`
 	Desc   string `json:"desc"`
+	// Rules are the eligibility rules on participant attributes.
+	Rules []Rule `json:"rules,omitempty"`
+	// GroupCap caps the amount of winners of the prize from the same group.
+	GroupCap *GroupCap `json:"group_cap,omitempty"`
+	// RepeatWinners decides whether winners of other prizes are eligible.
+	// It should be one of the Repeat* consts. Default is RepeatNone.
+	RepeatWinners string `json:"repeat_winners,omitempty"`
+	// RepeatPrizeNos are the prize nos whose winners are eligible
+	// if RepeatWinners is RepeatPrizes.
+	RepeatPrizeNos []int `json:"repeat_prize_nos,omitempty"`
+	// Alternates is the amount of alternates(standby winners)
+	// drawn alongside the winners by Draw.
+	Alternates int `json:"alternates,omitempty"`
 }
 
 type Blacklist struct {
@@ -36,30 +51,109 @@ type Blacklist struct {
 }
 
 type Lottery struct {
+	*state
+	// meta is the metadata of the operations. It's set by As.
+	meta *Meta
+}
+
+// state is the state of a lottery.
+// It's shared by the lottery and the ones returned by As.
+type state struct {
 	name         string
 	prizes       map[int]Prize
 	blacklists   map[int]Blacklist
 	participants map[string]Participant
-	winners      map[int][]Participant
-	mutex        *sync.Mutex
+	// normalizeID is the ID normalization of the imported participants.
+	// IDs of blacklists and history are normalized by it to match the
+	// participants. It's nil if IDs are not normalized.
+	normalizeID *IDNormalization
+	winners     map[int][]Winner
+	// rounds maps prize no to the last round number of the prize.
+	// It only increases, so round numbers are never reused.
+	rounds      map[int]int
+	rng         RNG
+	seeds       map[int][]byte
+	drawRecords map[int][]DrawRecord
+	groupCap    *GroupCap
+	history     *History
+	alternates  map[int][]Participant
+	// claims maps prize no to the claim statuses of its winners.
+	claims         map[int]map[string]string
+	claimTimeout   time.Duration
+	claimTimers    map[int]map[string]*time.Timer
+	onClaimTimeout ClaimTimeoutHandler
+	// revocations maps prize no to the revoked winners of the prize.
+	revocations    map[int][]Revocation
+	revokePolicies map[string]string
+	// journal is the append-only journal file. It's nil if not opened.
+	journal     *os.File
+	journalFile string
+	journalSeq  int
+	// undos and redos are the undo and redo history.
+	undos     []undoEntry
+	redos     []undoEntry
+	finalized bool
+	// audit is the audit log of the mutating operations.
+	audit []AuditEntry
+	// workspace is the data dir of the lottery.
+	workspace *Workspace
+	// store saves and loads the data of the lottery.
+	store Store
+	// signer signs and verifies the saved data. It's nil if not set.
+	signer Signer
+	mutex  *sync.Mutex
 }
 
+// Option configures a lottery created by New.
+type Option func(l *Lottery)
+
 type SaveData struct {
+	// Version is the version of the save format. See SaveVersion.
+	Version      int                    `json:"version,omitempty"`
 	Name         string                 `json:"name"`
 	Prizes       map[int]Prize          `json:"prizes"`
 	Blacklists   map[int]Blacklist      `json:"blacklists"`
 	Participants map[string]Participant `json:"participants"`
-	Winners      map[int][]Participant  `json:"winners"`
-	LastUpdated  string                 `json:"last_updated"`
-	Checksum     string                 `json:"checksum"`
+	// NormalizeID is the ID normalization of the imported participants.
+	NormalizeID *IDNormalization `json:"normalize_id,omitempty"`
+	Winners     map[int][]Winner `json:"winners"`
+	// LastRounds maps prize no to the last round number of the prize.
+	LastRounds  map[int]int               `json:"last_rounds,omitempty"`
+	DrawRecords map[int][]DrawRecord      `json:"draw_records,omitempty"`
+	GroupCap    *GroupCap                 `json:"group_cap,omitempty"`
+	Alternates  map[int][]Participant     `json:"alternates,omitempty"`
+	Claims      map[int]map[string]string `json:"claims,omitempty"`
+	Revocations map[int][]Revocation      `json:"revocations,omitempty"`
+	// RevokePolicies maps revoke reason to policy.
+	RevokePolicies map[string]string `json:"revoke_policies,omitempty"`
+	// Finalized means operations can not be undone.
+	Finalized bool `json:"finalized,omitempty"`
+	// Audit is the audit log of the mutating operations.
+	Audit       []AuditEntry `json:"audit,omitempty"`
+	LastUpdated string       `json:"last_updated"`
+	Checksum    string       `json:"checksum"`
+	// Signature is the signature over all other fields.
+	// It's nil if the lottery has no signer.
+	Signature *Signature `json:"signature,omitempty"`
+	// legacy is the signed message of the data migrated from an old version.
+	// See DecodeSaveData.
+	legacy []byte
 }
 
 const (
 	AppName = "lottery-go"
+
+	// RepeatNone means winners of other prizes are not eligible.
+	RepeatNone = "none"
+	// RepeatAny means winners of other prizes are eligible.
+	RepeatAny = "any"
+	// RepeatPrizes means only winners of the given prizes are eligible.
+	RepeatPrizes = "prizes"
 )
 
 var (
 	ErrParticipantsCSV               = fmt.Errorf("incorrect participants CSV")
+	ErrParticipantWeight             = fmt.Errorf("incorrect participant weight")
 	ErrPrizeNo                       = fmt.Errorf("incorrect prize no")
 	ErrWinnersExistBeforeDraw        = fmt.Errorf("winners exist before draw")
 	ErrPrizeAmount                   = fmt.Errorf("incorrect prize amount")
@@ -69,37 +163,72 @@ var (
 	ErrWinnersNotExistBeforeReDraw   = fmt.Errorf("winners don't exist before redraw")
 	ErrRedrawPrizeAmount             = fmt.Errorf("incorrect redraw prize amount")
 	ErrChecksum                      = fmt.Errorf("incorrect checksum")
-	AppDataDir                       string
+	ErrBatchAmount                   = fmt.Errorf("incorrect batch amount")
+	ErrPrizeDrawn                    = fmt.Errorf("all places of the prize are drawn")
+	ErrRepeatWinners                 = fmt.Errorf("incorrect repeat winners setting")
+	ErrAlternates                    = fmt.Errorf("incorrect amount of alternates")
+	ErrNoAvailableAlternates         = fmt.Errorf("no available alternates")
+
+	// AppDataDir is the dir of the default workspace: "<home dir>/lottery-go".
+	// It's not created until data is saved.
+	AppDataDir = defaultAppDataDir()
 )
 
-func init() {
-	dir, err := CreateAppDataDir()
-	if err != nil {
-		log.Printf("CreateAppDataDir() error: %v", err)
-		return
+// WithRNG sets the source of randomness used by the draws.
+// The default one is NewCryptoRNG().
+func WithRNG(rng RNG) Option {
+	return func(l *Lottery) {
+		l.rng = rng
 	}
-	AppDataDir = dir
 }
 
-func New(name string) *Lottery {
-	l := &Lottery{
-		name,
-		make(map[int]Prize),
-		make(map[int]Blacklist),
-		make(map[string]Participant),
-		make(map[int][]Participant),
-		&sync.Mutex{},
+func New(name string, options ...Option) *Lottery {
+	workspace := NewWorkspace(AppDataDir)
+
+	l := &Lottery{state: &state{
+		name:           name,
+		prizes:         make(map[int]Prize),
+		blacklists:     make(map[int]Blacklist),
+		participants:   make(map[string]Participant),
+		winners:        make(map[int][]Winner),
+		rounds:         make(map[int]int),
+		rng:            NewCryptoRNG(),
+		seeds:          make(map[int][]byte),
+		drawRecords:    make(map[int][]DrawRecord),
+		alternates:     make(map[int][]Participant),
+		claims:         make(map[int]map[string]string),
+		claimTimers:    make(map[int]map[string]*time.Timer),
+		revocations:    make(map[int][]Revocation),
+		revokePolicies: make(map[string]string),
+		workspace:      workspace,
+		store:          workspace.store,
+		mutex:          &sync.Mutex{},
+	}}
+
+	for _, option := range options {
+		option(l)
 	}
 
 	return l
 }
 
-func (l *Lottery) SetPrize(no int, name string, amount int, desc string) {
+func (l *Lottery) SetPrize(no int, name string, amount int, desc string) error {
 	l.mutex.Lock()
 	defer l.mutex.Unlock()
 
-	prize := Prize{no, name, amount, desc}
+	// Keep other settings(e.g. rules) of the existing prize.
+	prize := l.prizes[no]
+	prize.No = no
+	prize.Name = name
+	prize.Amount = amount
+	prize.Desc = desc
+
+	if err := l.record(Event{Type: EventSetPrize, Prize: &prize, Meta: l.meta}); err != nil {
+		return err
+	}
+
 	l.prizes[no] = prize
+	return nil
 }
 
 func (l *Lottery) Prize(no int) Prize {
@@ -109,37 +238,17 @@ func (l *Lottery) Prize(no int) Prize {
 	return l.prizes[no]
 }
 
+// LoadPrizesCSV loads prizes from the CSV.
+// The first row is the header.
+// The columns are No, Name, Amount, Desc and the optional Alternates.
+// Existing prizes with the same nos keep the settings which are not in the
+// CSV(e.g. rules and group caps), and the alternates if it's blank.
+// Prizes which are not in the CSV are removed.
+// It returns an *ImportError with the validation report if the CSV has
+// errors. Use ImportPrizesCSV to skip the rows with errors.
 func (l *Lottery) LoadPrizesCSV(r io.Reader) error {
-	l.mutex.Lock()
-	defer l.mutex.Unlock()
-
-	reader := csv.NewReader(r)
-	rows, err := reader.ReadAll()
-	if err != nil {
-		return err
-	}
-
-	l.prizes = make(map[int]Prize)
-	for i := 1; i < len(rows); i++ {
-		row := rows[i]
-
-		if len(row) != 4 {
-			return ErrParticipantsCSV
-		}
-		no, err := strconv.Atoi(strings.Trim(row[0], " "))
-		if err != nil {
-			return err
-		}
-		name := row[1]
-		amount, err := strconv.Atoi(strings.Trim(row[2], " "))
-		if err != nil {
-			return err
-		}
-		desc := row[3]
-
-		l.prizes[no] = Prize{no, name, amount, desc}
-	}
-	return nil
+	_, err := l.importPrizesCSV(r, ImportSpec{}, false)
+	return err
 }
 
 func (l *Lottery) LoadPrizesCSVFile(file string) error {
@@ -183,12 +292,17 @@ func (l *Lottery) Prizes(descOrder bool) []Prize {
 	return prizeMapToSlice(l.prizes, descOrder)
 }
 
-func (l *Lottery) SetBlacklist(minPrizeNo int, IDs []string) {
+func (l *Lottery) SetBlacklist(minPrizeNo int, IDs []string) error {
 	l.mutex.Lock()
 	defer l.mutex.Unlock()
 
 	blacklist := Blacklist{minPrizeNo, IDs}
+	if err := l.record(Event{Type: EventSetBlacklist, Blacklist: &blacklist, Meta: l.meta}); err != nil {
+		return err
+	}
+
 	l.blacklists[minPrizeNo] = blacklist
+	return nil
 }
 
 func (l *Lottery) LoadBlacklistsJSONFile(f string) error {
@@ -200,7 +314,17 @@ func (l *Lottery) LoadBlacklistsJSONFile(f string) error {
 		return err
 	}
 
-	return json.Unmarshal(buf, &l.blacklists)
+	blacklists := make(map[int]Blacklist)
+	if err := json.Unmarshal(buf, &blacklists); err != nil {
+		return err
+	}
+
+	if err := l.record(Event{Type: EventLoadBlacklists, Blacklists: blacklists, Meta: l.meta}); err != nil {
+		return err
+	}
+
+	l.blacklists = blacklists
+	return nil
 }
 
 func blacklistMapToSlice(m map[int]Blacklist) []Blacklist {
@@ -230,27 +354,17 @@ func (l *Lottery) Blacklists() []Blacklist {
 	return blacklistMapToSlice(l.blacklists)
 }
 
+// LoadParticipantsCSV loads participants from the CSV.
+// The first row is the header.
+// The first 2 columns are ID and Name.
+// The column named "Weight" is the optional weight of the participant.
+// Other columns are loaded as attributes named by the header.
+// It returns an *ImportError with the validation report if the CSV has
+// errors, e.g. duplicate IDs or blank names. Use ImportParticipantsCSV to
+// skip the rows with errors.
 func (l *Lottery) LoadParticipantsCSV(r io.Reader) error {
-	l.mutex.Lock()
-	defer l.mutex.Unlock()
-
-	reader := csv.NewReader(r)
-	rows, err := reader.ReadAll()
-	if err != nil {
-		return err
-	}
-
-	l.participants = make(map[string]Participant)
-	for i := 1; i < len(rows); i++ {
-		row := rows[i]
-		if len(row) != 2 {
-			return ErrParticipantsCSV
-		}
-		ID := row[0]
-		name := row[1]
-		l.participants[ID] = Participant{ID, name}
-	}
-	return nil
+	_, err := l.importParticipantsCSV(r, ImportSpec{}, false)
+	return err
 }
 
 func (l *Lottery) LoadParticipantsCSVFile(file string) error {
@@ -270,6 +384,11 @@ func participantMapToSlice(m map[string]Participant) []Participant {
 		participants = append(participants, p)
 	}
 
+	// Sort participants by ID to make draws reproducible with a seeded RNG.
+	sort.Slice(participants, func(i, j int) bool {
+		return participants[i].ID < participants[j].ID
+	})
+
 	return participants
 }
 
@@ -300,46 +419,146 @@ func copyParticipantMap(m map[string]Participant) map[string]Participant {
 	return copiedMap
 }
 
+// repeatAllowed reports whether winners of the other prize are eligible for the prize.
+func (p Prize) repeatAllowed(otherPrizeNo int) bool {
+	if otherPrizeNo == p.No {
+		return false
+	}
+
+	switch p.RepeatWinners {
+	case RepeatAny:
+		return true
+	case RepeatPrizes:
+		for _, no := range p.RepeatPrizeNos {
+			if no == otherPrizeNo {
+				return true
+			}
+		}
+		return false
+	default:
+		return false
+	}
+}
+
+// SetPrizeRepeatWinners sets whether winners of other prizes are eligible
+// for the prize. The mode should be one of the Repeat* consts.
+// The prize nos are used only if mode is RepeatPrizes.
+// A participant can win a prize only once in any mode.
+func (l *Lottery) SetPrizeRepeatWinners(prizeNo int, mode string, prizeNos []int) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	prize, ok := l.prizes[prizeNo]
+	if !ok {
+		return ErrPrizeNo
+	}
+
+	switch mode {
+	case "", RepeatNone, RepeatAny:
+		prizeNos = nil
+	case RepeatPrizes:
+	default:
+		return ErrRepeatWinners
+	}
+
+	prize.RepeatWinners = mode
+	prize.RepeatPrizeNos = prizeNos
+	if err := l.record(Event{Type: EventSetPrizeRepeatWinners, Prize: &prize, Meta: l.meta}); err != nil {
+		return err
+	}
+
+	l.prizes[prizeNo] = prize
+	return nil
+}
+
 func (l *Lottery) availableParticipants(prizeNo int) []Participant {
 	participants := copyParticipantMap(l.participants)
+	prize := l.prizes[prizeNo]
 
-	// Remove winners
-	for _, winners := range l.winners {
+	// Remove winners.
+	// Winners of other prizes are kept if the prize allows repeat winners.
+	for no, winners := range l.winners {
+		if prize.repeatAllowed(no) {
+			continue
+		}
 		for _, winner := range winners {
 			delete(participants, winner.ID)
 		}
 	}
 
+	// Remove participants who forfeited or declined the prize and
+	// revoked winners excluded by the revoke policies.
+	for ID := range participants {
+		if l.gaveUp(prizeNo, ID) || l.excluded(prizeNo, ID) {
+			delete(participants, ID)
+		}
+	}
+
 	// Remove blacklists
 	for _, blacklist := range l.blacklists {
 		if blacklist.MinPrizeNo > prizeNo {
 			for _, ID := range blacklist.IDs {
-				delete(participants, ID)
+				delete(participants, l.normalize(ID))
 			}
 		}
 	}
 
+	// Remove participants which do not match the eligibility rules.
+	rules := prize.Rules
+	for ID, p := range participants {
+		if !matchRules(rules, p) {
+			delete(participants, ID)
+		}
+	}
+
+	// Apply history rules.
+	l.applyHistoryRules(rules, participants)
+
 	return participantMapToSlice(participants)
 }
 
+// canonicalParticipants returns the participants with the original data.
+// The available participants may have effective weights by history rules.
+func (l *Lottery) canonicalParticipants(participants []Participant) []Participant {
+	s := []Participant{}
+
+	for _, p := range participants {
+		if original, ok := l.participants[p.ID]; ok {
+			p = original
+		}
+		s = append(s, p)
+	}
+
+	return s
+}
+
 func (l *Lottery) AvailableParticipants(prizeNo int) []Participant {
 	l.mutex.Lock()
 	defer l.mutex.Unlock()
 
-	return l.availableParticipants(prizeNo)
+	return l.canonicalParticipants(l.availableParticipants(prizeNo))
 }
 
-func (l *Lottery) Winners(prizeNo int) []Participant {
+func (l *Lottery) Winners(prizeNo int) []Winner {
 	l.mutex.Lock()
 	defer l.mutex.Unlock()
 
 	if _, ok := l.winners[prizeNo]; !ok {
-		return []Participant{}
+		return []Winner{}
 	}
 
 	return l.winners[prizeNo]
 }
 
+// weight returns the weight of the participant.
+// It returns the default weight(1) if the weight is not set.
+func (p Participant) weight() int {
+	if p.Weight < 1 {
+		return 1
+	}
+	return p.Weight
+}
+
 func removeParticipant(s []Participant, i int) []Participant {
 	l := len(s)
 	if l <= 0 {
@@ -354,13 +573,18 @@ func removeParticipant(s []Participant, i int) []Participant {
 	return s[:l-1]
 }
 
-func draw(prizeAmount int, participants []Participant) []Participant {
+func draw(rng RNG, prizeAmount int, participants []Participant, caps []*capState) []Participant {
 	winners := []Participant{}
 
 	if prizeAmount <= 0 || len(participants) <= 0 {
 		return winners
 	}
 
+	// Remove participants whose groups reach the caps.
+	if len(caps) > 0 {
+		participants = removeCapped(participants, caps)
+	}
+
 	// Check prize amount.
 	amount := prizeAmount
 	// If participants amount < prize amount,
@@ -369,21 +593,136 @@ func draw(prizeAmount int, participants []Participant) []Participant {
 		amount = len(participants)
 	}
 
-	for i := 0; i < amount; i++ {
-		rand.Seed(time.Now().UnixNano())
-		index := rand.Intn(len(participants))
-		winners = append(winners, participants[index])
+	// Weighted sampling without replacement:
+	// pick a participant with the probability proportional to its weight,
+	// then remove it from the participants so it can win only once.
+	// It's the same as uniform sampling if all weights are 1.
+	total := 0
+	for _, p := range participants {
+		total += p.weight()
+	}
+
+	for i := 0; i < amount && len(participants) > 0; i++ {
+		n := rng.Intn(total)
+		index := 0
+		for ; index < len(participants)-1; index++ {
+			n -= participants[index].weight()
+			if n < 0 {
+				break
+			}
+		}
+
+		winner := participants[index]
+		total -= winner.weight()
+		winners = append(winners, winner)
 		participants = removeParticipant(participants, index)
+
+		if len(caps) == 0 {
+			continue
+		}
+
+		// Update the caps and remove participants
+		// whose groups reach the caps.
+		for _, c := range caps {
+			if g, ok := c.groups[winner.ID]; ok {
+				c.remaining[g]--
+			}
+		}
+
+		participants = removeCapped(participants, caps)
+		total = 0
+		for _, p := range participants {
+			total += p.weight()
+		}
 	}
 
 	return winners
 }
 
-func (l *Lottery) Draw(prizeNo int) ([]Participant, error) {
+// drawPrize draws the amount of winners of the prize from the participants.
+// Then it draws the extra amount of alternates from the remaining
+// participants. The alternates are appended to the winners.
+// It applies the group caps and uses the pending commit-reveal seed if any.
+func (l *Lottery) drawPrize(prizeNo int, amount int, extra int, participants []Participant) ([]Participant, error) {
+	caps := l.groupCaps(prizeNo, participants)
+	if err := checkGroupCaps(prizeNo, amount, participants, caps); err != nil {
+		return []Participant{}, err
+	}
+
+	winners, record, err := l.drawWithCaps(prizeNo, amount, extra, participants, caps, "", "")
+	if err != nil {
+		return []Participant{}, err
+	}
+
+	if record != nil {
+		l.drawRecords[prizeNo] = append(l.drawRecords[prizeNo], *record)
+		delete(l.seeds, prizeNo)
+	}
+
+	return winners, nil
+}
+
+// drawWithCaps draws the amount of winners and the extra amount of alternates
+// of the prize from the participants under the group caps.
+// There may be less alternates than the extra amount.
+// It uses the pending commit-reveal seed if any and returns the draw record
+// to reveal the seed. Otherwise, the record is nil.
+// The attribute and the stratum are set by stratified draws to derive the
+// seed of the stratum. See drawSeed.
+func (l *Lottery) drawWithCaps(prizeNo int, amount int, extra int, participants []Participant, caps []*capState, attribute string, stratum string) ([]Participant, *DrawRecord, error) {
+	// Sort participants by ID to make draws reproducible with a seeded RNG.
+	sort.Slice(participants, func(i, j int) bool {
+		return participants[i].ID < participants[j].ID
+	})
+
+	// draw() reorders the participants, get eligible ones before drawing.
+	eligible := make([]Participant, len(participants))
+	copy(eligible, participants)
+
+	capRecords := []CapRecord{}
+	for _, c := range caps {
+		capRecords = append(capRecords, c.record(eligible))
+	}
+
+	rng := l.rng
+	seed, committed := l.seeds[prizeNo]
+	if committed {
+		rng = NewSeededRNG(drawSeed(seed, attribute, stratum))
+	}
+
+	winners := l.canonicalParticipants(draw(rng, amount+extra, participants, caps))
+
+	// Some groups may reach the caps before all winners are drawn.
+	expected := amount
+	if expected > len(eligible) {
+		expected = len(eligible)
+	}
+	if len(winners) < expected {
+		// Draws without caps always draw the expected amount.
+		if len(caps) == 0 {
+			return []Participant{}, nil, ErrNoAvailableParticipants
+		}
+
+		c := caps[len(caps)-1]
+		return []Participant{}, nil, &GroupCapError{prizeNo, c.attribute, c.max, expected, len(winners)}
+	}
+
+	if !committed {
+		return winners, nil, nil
+	}
+
+	if len(capRecords) == 0 {
+		capRecords = nil
+	}
+	record := newDrawRecord(prizeNo, amount+extra, seed, attribute, stratum, eligible, capRecords, winners)
+	return winners, &record, nil
+}
+
+func (l *Lottery) Draw(prizeNo int) ([]Winner, error) {
 	l.mutex.Lock()
 	defer l.mutex.Unlock()
 
-	winners := []Participant{}
+	winners := []Winner{}
 
 	if _, ok := l.prizes[prizeNo]; !ok {
 		return winners, ErrPrizeNo
@@ -403,15 +742,34 @@ func (l *Lottery) Draw(prizeNo int) ([]Participant, error) {
 		return winners, ErrNoAvailableParticipants
 	}
 
-	winners = draw(amount, participants)
+	r := l.rollbackPoint(prizeNo)
+
+	// Draw the alternates at the same time from the same pool.
+	drawn, err := l.drawPrize(prizeNo, amount, l.prizes[prizeNo].Alternates, participants)
+	if err != nil {
+		return winners, err
+	}
+
+	if len(drawn) > amount {
+		l.alternates[prizeNo] = drawn[amount:]
+		drawn = drawn[:amount]
+	}
+
+	l.winners[prizeNo] = []Winner{}
+	winners = l.addWinners(prizeNo, drawn, l.nextRound(prizeNo))
+	l.pushUndo(EventDraw, prizeNo, r.state)
+	if err := l.recordPrize(Event{Type: EventDraw, PrizeNo: prizeNo, Meta: l.meta}, r); err != nil {
+		return []Winner{}, err
+	}
 
-	l.winners[prizeNo] = winners
 	return winners, nil
 }
 
-// Revoke revokes the winners of the given prize.
+// Revoke revokes the winners of the given prize with the reason code.
 // It'll remove revoked winners from winners of the prize.
-func (l *Lottery) Revoke(prizeNo int, revokedWinners []Participant) error {
+// The policy of the reason decides whether the revoked winners are
+// available again. See SetRevokePolicy.
+func (l *Lottery) Revoke(prizeNo int, revokedWinners []Participant, reason string) error {
 	l.mutex.Lock()
 	defer l.mutex.Unlock()
 
@@ -429,24 +787,37 @@ func (l *Lottery) Revoke(prizeNo int, revokedWinners []Participant) error {
 	}
 
 	// Remove original winners for the prize before re-draw.
-	originalWinnerMap := participantSliceToMap(l.winners[prizeNo])
+	revokedWinnerMap := participantSliceToMap(revokedWinners)
 
 	for _, revokedWinner := range revokedWinners {
-		if _, ok := originalWinnerMap[revokedWinner.ID]; !ok {
+		if l.winnerIndex(prizeNo, revokedWinner.ID) < 0 {
 			return ErrRevokedWinnerNotMatch
 		}
-		delete(originalWinnerMap, revokedWinner.ID)
 	}
 
-	l.winners[prizeNo] = participantMapToSlice(originalWinnerMap)
-	return nil
+	r := l.rollbackPoint(prizeNo)
+
+	// Keep the order of the remaining winners.
+	winners := []Winner{}
+	for _, winner := range l.winners[prizeNo] {
+		if _, ok := revokedWinnerMap[winner.ID]; ok {
+			l.revokeWinner(prizeNo, winner, reason)
+			continue
+		}
+		winners = append(winners, winner)
+	}
+
+	l.winners[prizeNo] = winners
+	l.updatePositions(prizeNo)
+	l.pushUndo(EventRevoke, prizeNo, r.state)
+	return l.recordPrize(Event{Type: EventRevoke, PrizeNo: prizeNo, IDs: participantIDs(revokedWinners), Reason: reason, Meta: l.meta}, r)
 }
 
-func (l *Lottery) Redraw(prizeNo int, amount int) ([]Participant, error) {
+func (l *Lottery) Redraw(prizeNo int, amount int) ([]Winner, error) {
 	l.mutex.Lock()
 	defer l.mutex.Unlock()
 
-	winners := []Participant{}
+	winners := []Winner{}
 
 	if _, ok := l.prizes[prizeNo]; !ok {
 		return winners, ErrPrizeNo
@@ -469,55 +840,274 @@ func (l *Lottery) Redraw(prizeNo int, amount int) ([]Participant, error) {
 		return winners, ErrNoAvailableParticipants
 	}
 
+	r := l.rollbackPoint(prizeNo)
+
 	// Get new winners.
-	winners = draw(amount, participants)
+	drawn, err := l.drawPrize(prizeNo, amount, 0, participants)
+	if err != nil {
+		return winners, err
+	}
 
 	// Append new winners and original winners.
-	l.winners[prizeNo] = append(l.winners[prizeNo], winners...)
+	winners = l.addWinners(prizeNo, drawn, l.nextRound(prizeNo))
+	l.pushUndo(EventRedraw, prizeNo, r.state)
+	if err := l.recordPrize(Event{Type: EventRedraw, PrizeNo: prizeNo, Amount: amount, Meta: l.meta}, r); err != nil {
+		return []Winner{}, err
+	}
+
 	return winners, nil
 }
 
-func (l *Lottery) AllWinners() map[int][]Participant {
+// Replace revokes the winners of the prize with the reason code and draws
+// the replacements in one operation. The replacements take the positions
+// and the rounds of the revoked winners.
+// The revoked winners are not available for the replacements.
+// If there're not enough available participants for the replacements,
+// it returns an error and nothing is changed.
+// It returns the replacements in the order of the positions.
+func (l *Lottery) Replace(prizeNo int, revokedIDs []string, reason string) ([]Winner, error) {
 	l.mutex.Lock()
 	defer l.mutex.Unlock()
 
-	return l.winners
+	replacements := []Winner{}
+
+	if _, ok := l.prizes[prizeNo]; !ok {
+		return replacements, ErrPrizeNo
+	}
+
+	if l.prizes[prizeNo].Amount < 1 {
+		return replacements, ErrPrizeAmount
+	}
+
+	if _, ok := l.winners[prizeNo]; !ok {
+		return replacements, ErrNoOriginalWinnersBeforeRedraw
+	}
+
+	revoked := make(map[string]bool)
+	for _, ID := range revokedIDs {
+		if l.winnerIndex(prizeNo, ID) < 0 {
+			return replacements, ErrRevokedWinnerNotMatch
+		}
+		revoked[ID] = true
+	}
+
+	if len(revoked) == 0 {
+		return replacements, ErrRedrawPrizeAmount
+	}
+
+	r := l.rollbackPoint(prizeNo)
+
+	// Draw without the revoked winners to apply the group caps correctly.
+	original := l.winners[prizeNo]
+	remaining := []Winner{}
+	for _, winner := range original {
+		if !revoked[winner.ID] {
+			remaining = append(remaining, winner)
+		}
+	}
+	l.winners[prizeNo] = remaining
+
+	participants := []Participant{}
+	for _, p := range l.availableParticipants(prizeNo) {
+		if !revoked[p.ID] {
+			participants = append(participants, p)
+		}
+	}
+
+	if len(participants) < len(revoked) {
+		l.winners[prizeNo] = original
+		return replacements, ErrNoAvailableParticipants
+	}
+
+	drawn, err := l.drawPrize(prizeNo, len(revoked), 0, participants)
+	if err != nil {
+		l.winners[prizeNo] = original
+		return replacements, err
+	}
+
+	// Put the replacements into the positions of the revoked winners.
+	drawnWinners := newWinners(drawn, 0)
+	winners := []Winner{}
+	for _, winner := range original {
+		if !revoked[winner.ID] {
+			winners = append(winners, winner)
+			continue
+		}
+
+		l.revokeWinner(prizeNo, winner, reason)
+
+		replacement := drawnWinners[len(replacements)]
+		replacement.Round = winner.Round
+		replacement.Replaces = winner.ID
+		winners = append(winners, replacement)
+		replacements = append(replacements, replacement)
+	}
+
+	l.winners[prizeNo] = winners
+	l.updatePositions(prizeNo)
+
+	for i := range replacements {
+		replacements[i] = winners[l.winnerIndex(prizeNo, replacements[i].ID)]
+	}
+
+	l.markDrawn(prizeNo, replacements)
+	if err := l.recordPrize(Event{Type: EventReplace, PrizeNo: prizeNo, IDs: revokedIDs, Reason: reason, Meta: l.meta}, r); err != nil {
+		return []Winner{}, err
+	}
+
+	return replacements, nil
 }
 
-func (l *Lottery) ClearWinners(prizeNo int) {
+// nextRound returns the round number of the next draw of the prize and
+// records it as the last round of the prize.
+// The winners are checked for the data saved without the last rounds.
+func (l *Lottery) nextRound(prizeNo int) int {
+	round := l.rounds[prizeNo]
+
+	for _, winner := range l.winners[prizeNo] {
+		if winner.Round > round {
+			round = winner.Round
+		}
+	}
+
+	round++
+	l.rounds[prizeNo] = round
+	return round
+}
+
+// DrawBatch draws the given amount of the remaining places of the prize.
+// It's used to reveal the winners of a prize in several rounds.
+// If the amount is greater than the remaining places,
+// it draws all the remaining places.
+// It returns the new winners and the round number of them.
+func (l *Lottery) DrawBatch(prizeNo int, amount int) ([]Winner, int, error) {
 	l.mutex.Lock()
 	defer l.mutex.Unlock()
 
-	// Clear the winner slice.
-	l.winners[prizeNo] = []Participant{}
+	winners := []Winner{}
+
+	if _, ok := l.prizes[prizeNo]; !ok {
+		return winners, 0, ErrPrizeNo
+	}
+
+	if l.prizes[prizeNo].Amount < 1 {
+		return winners, 0, ErrPrizeAmount
+	}
+
+	if amount < 1 {
+		return winners, 0, ErrBatchAmount
+	}
+
+	remaining := l.prizes[prizeNo].Amount - len(l.winners[prizeNo])
+	if remaining < 1 {
+		return winners, 0, ErrPrizeDrawn
+	}
+
+	if amount > remaining {
+		amount = remaining
+	}
+
+	participants := l.availableParticipants(prizeNo)
+	if len(participants) == 0 {
+		return winners, 0, ErrNoAvailableParticipants
+	}
+
+	r := l.rollbackPoint(prizeNo)
+
+	drawn, err := l.drawPrize(prizeNo, amount, 0, participants)
+	if err != nil {
+		return winners, 0, err
+	}
+
+	round := l.nextRound(prizeNo)
+	winners = l.addWinners(prizeNo, drawn, round)
+	if err := l.recordPrize(Event{Type: EventDrawBatch, PrizeNo: prizeNo, Amount: amount, Meta: l.meta}, r); err != nil {
+		return []Winner{}, 0, err
+	}
+
+	return winners, round, nil
 }
 
-func (l *Lottery) ClearAllWinners() {
+// WinnerRound returns the round number in which the winner of the prize
+// was drawn. It returns 0 if the participant is not a winner of the prize.
+func (l *Lottery) WinnerRound(prizeNo int, ID string) int {
 	l.mutex.Lock()
 	defer l.mutex.Unlock()
 
-	l.winners = make(map[int][]Participant)
+	if i := l.winnerIndex(prizeNo, ID); i >= 0 {
+		return l.winners[prizeNo][i].Round
+	}
+	return 0
 }
 
-func makeDataFileName(name string) string {
-	f := fmt.Sprintf("%X.json", md5.Sum([]byte(name)))
-	return path.Join(AppDataDir, f)
+// WinnerPrizes returns the sorted nos of the prizes won by the participant.
+// A participant may win several prizes if prizes allow repeat winners.
+func (l *Lottery) WinnerPrizes(ID string) []int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	prizeNos := []int{}
+	for no, winners := range l.winners {
+		for _, winner := range winners {
+			if winner.ID == ID {
+				prizeNos = append(prizeNos, no)
+				break
+			}
+		}
+	}
+
+	sort.Ints(prizeNos)
+	return prizeNos
 }
 
-func CreateAppDataDir() (string, error) {
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return "", err
+func (l *Lottery) AllWinners() map[int][]Winner {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return l.winners
+}
+
+func (l *Lottery) ClearWinners(prizeNo int) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	r := l.rollbackPoint(prizeNo)
+
+	// Clear the winner slice.
+	l.winners[prizeNo] = []Winner{}
+	delete(l.alternates, prizeNo)
+	l.clearClaims(prizeNo)
+	delete(l.revocations, prizeNo)
+	l.pushUndo(EventClearWinners, prizeNo, r.state)
+	return l.recordPrize(Event{Type: EventClearWinners, PrizeNo: prizeNo, Meta: l.meta}, r)
+}
+
+func (l *Lottery) ClearAllWinners() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if err := l.record(Event{Type: EventClearAllWinners, Meta: l.meta}); err != nil {
+		return err
 	}
 
-	dir := path.Join(homeDir, AppName)
-	if err := os.MkdirAll(dir, 0755); err != nil {
+	l.winners = make(map[int][]Winner)
+	l.alternates = make(map[int][]Participant)
+	l.stopClaimTimers()
+	l.claims = make(map[int]map[string]string)
+	l.revocations = make(map[int][]Revocation)
+	l.clearUndo()
+	return nil
+}
+
+// CreateAppDataDir creates AppDataDir if it does not exist.
+func CreateAppDataDir() (string, error) {
+	if err := os.MkdirAll(AppDataDir, 0755); err != nil {
 		return "", err
 	}
-	return dir, nil
+	return AppDataDir, nil
 }
 
-func computeWinnersHash(winners map[int][]Participant) []byte {
+func computeWinnersHash(winners map[int][]Winner) []byte {
 	var arr []int
 
 	// Sort winner map by key
@@ -542,18 +1132,28 @@ func computeWinnersHash(winners map[int][]Participant) []byte {
 	return h.Sum(nil)
 }
 
-func (l *Lottery) Save(w io.Writer) error {
-	l.mutex.Lock()
-	defer l.mutex.Unlock()
-
+// saveData returns the data to save of the lottery.
+// The data is signed if the lottery has a signer.
+func (l *Lottery) saveData() (SaveData, error) {
 	tm := time.Now()
 
 	data := SaveData{
+		SaveVersion,
 		l.name,
 		l.prizes,
 		l.blacklists,
 		l.participants,
+		l.normalizeID,
 		l.winners,
+		l.rounds,
+		l.drawRecords,
+		l.groupCap,
+		l.alternates,
+		l.claims,
+		l.revocations,
+		l.revokePolicies,
+		l.finalized,
+		l.audit,
 		fmt.Sprintf("%04d-%02d-%02d %02d:%02d:%02d",
 			tm.Year(),
 			tm.Month(),
@@ -563,45 +1163,99 @@ func (l *Lottery) Save(w io.Writer) error {
 			tm.Second(),
 		),
 		fmt.Sprintf("%X", computeWinnersHash(l.winners)),
+		nil,
+		nil,
 	}
 
-	enc := json.NewEncoder(w)
-	enc.SetIndent("", "    ")
-	return enc.Encode(&data)
+	if l.signer != nil {
+		if err := SignSaveData(&data, l.signer); err != nil {
+			return data, err
+		}
+	}
+
+	return data, nil
 }
 
-func (l *Lottery) SaveToFile() error {
-	dataFile := makeDataFileName(l.name)
+func (l *Lottery) Save(w io.Writer) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
 
-	f, err := os.Create(dataFile)
+	data, err := l.saveData()
 	if err != nil {
 		return err
 	}
-	defer f.Close()
 
-	return l.Save(f)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "    ")
+	return enc.Encode(&data)
+}
+
+// SaveToFile saves the data of the lottery to the store of the lottery.
+// It's the same as SaveToStore. With the default store, the data is saved as
+// JSON file in the workspace. See FileStore for more information.
+func (l *Lottery) SaveToFile() error {
+	return l.SaveToStore()
 }
 
 func (l *Lottery) Load(r io.Reader) error {
 	l.mutex.Lock()
 	defer l.mutex.Unlock()
 
-	data := SaveData{}
-	dec := json.NewDecoder(r)
+	data, err := DecodeSaveData(r)
+	if err != nil {
+		return err
+	}
 
-	if err := dec.Decode(&data); err != nil {
+	if err := l.load(data); err != nil {
 		return err
 	}
 
+	return l.record(Event{Type: EventLoad, Meta: l.meta})
+}
+
+// verify verifies the saved data.
+// If the lottery has a signer, the data must be signed by the signer.
+func (l *Lottery) verify(data SaveData) error {
+	if l.signer != nil {
+		if err := VerifySaveData(data, l.signer); err != nil {
+			return err
+		}
+	}
+
 	checksum := computeWinnersHash(data.Winners)
 	if fmt.Sprintf("%X", checksum) != data.Checksum {
 		return ErrChecksum
 	}
 
+	return nil
+}
+
+// load replaces the state of the lottery with the saved data.
+func (l *Lottery) load(data SaveData) error {
+	if err := l.verify(data); err != nil {
+		return err
+	}
+
 	l.prizes = data.Prizes
 	l.blacklists = data.Blacklists
 	l.participants = data.Participants
+	l.normalizeID = data.NormalizeID
 	l.winners = data.Winners
+	l.rounds = data.LastRounds
+	l.drawRecords = data.DrawRecords
+	l.groupCap = data.GroupCap
+	l.alternates = data.Alternates
+	l.stopClaimTimers()
+	l.claims = data.Claims
+	l.revocations = data.Revocations
+	l.revokePolicies = data.RevokePolicies
+	l.finalized = data.Finalized
+	// The audit log is append-only. Keep the entries after the saved data
+	// (e.g. an older backup).
+	if len(data.Audit) > len(l.audit) {
+		l.audit = data.Audit
+	}
+	l.clearUndo()
 
 	// Check if map is nil
 	if l.prizes == nil {
@@ -617,29 +1271,48 @@ func (l *Lottery) Load(r io.Reader) error {
 	}
 
 	if l.winners == nil {
-		l.winners = make(map[int][]Participant)
+		l.winners = make(map[int][]Winner)
 	}
 
-	return nil
-}
+	if l.rounds == nil {
+		l.rounds = make(map[int]int)
+	}
 
-func (l *Lottery) LoadFromFile() error {
-	dataFile := makeDataFileName(l.name)
+	if l.drawRecords == nil {
+		l.drawRecords = make(map[int][]DrawRecord)
+	}
 
-	f, err := os.Open(dataFile)
-	if err != nil {
-		return err
+	if l.alternates == nil {
+		l.alternates = make(map[int][]Participant)
 	}
-	defer f.Close()
 
-	return l.Load(f)
-}
+	if l.claims == nil {
+		l.claims = make(map[int]map[string]string)
+	}
 
-func (l *Lottery) DataFileExists() bool {
-	dataFile := makeDataFileName(l.name)
+	if l.revocations == nil {
+		l.revocations = make(map[int][]Revocation)
+	}
 
-	if _, err := os.Stat(dataFile); os.IsNotExist(err) {
-		return false
+	if l.revokePolicies == nil {
+		l.revokePolicies = make(map[string]string)
 	}
-	return true
+
+	// Restart claim timers of pending winners.
+	l.startClaimTimers()
+
+	return nil
+}
+
+// LoadFromFile loads the data of the lottery from the store of the lottery.
+// It's the same as LoadFromStore.
+func (l *Lottery) LoadFromFile() error {
+	return l.LoadFromStore()
+}
+
+// DataFileExists reports whether the lottery is saved in the store of the
+// lottery. It returns false if it fails to check. See Stored.
+func (l *Lottery) DataFileExists() bool {
+	exists, _ := l.Stored()
+	return exists
 }
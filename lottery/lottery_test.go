@@ -2,6 +2,7 @@ package lottery_test
 
 import (
 	"log"
+	"os"
 
 	"github.com/northbright/lottery-go/lottery"
 )
@@ -13,8 +14,17 @@ func Example() {
 		blacklistsJSON  = "settings/blacklists.example.json"
 	)
 
+	// Create a workspace for the data files.
+	dir, err := os.MkdirTemp("", "lottery-go")
+	if err != nil {
+		log.Printf("MkdirTemp() error: %v", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+	ws := lottery.NewWorkspace(dir)
+
 	// Create a lottery.
-	l := lottery.New("New Year Party Lucky Draw")
+	l := ws.New("New Year Party Lucky Draw")
 
 	if err := l.LoadParticipantsCSVFile(participantsCSV); err != nil {
 		log.Printf("LoadParticipantsCSVFile() error: %v", err)
@@ -63,8 +73,8 @@ func Example() {
 	log.Printf("winners of prize no.5: %v", winners)
 
 	// Revoke old winners and redraw.
-	revokedWinners := []lottery.Participant{winners[0], winners[1]}
-	if err := l.Revoke(5, revokedWinners); err != nil {
+	revokedWinners := []lottery.Participant{winners[0].Participant, winners[1].Participant}
+	if err := l.Revoke(5, revokedWinners, lottery.ReasonAbsent); err != nil {
 		log.Printf("revoke winners of prize no.5 error: %v", err)
 		return
 	}
@@ -0,0 +1,250 @@
+package lottery
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Migration upgrades the JSON object of the saved data of a version to the
+// next version in place.
+type Migration func(doc map[string]interface{}) error
+
+const (
+	// SaveVersion is the version of the save format.
+	// Saved data without version is version 0.
+	SaveVersion = 1
+)
+
+var (
+	ErrSaveVersion = fmt.Errorf("save format version is not supported")
+	ErrSaveData    = fmt.Errorf("incorrect saved data")
+
+	// migrations maps each version to the migration which upgrades the saved
+	// data of the version to the next version.
+	// Increase SaveVersion and add a migration when the save format changes.
+	migrations = map[int]Migration{
+		0: migrateV0,
+	}
+)
+
+// migrateV0 migrates the saved data of version 0.
+// Version 0 saves winners as participants, the rounds of the winners in a
+// separated map and does not save the positions.
+func migrateV0(doc map[string]interface{}) error {
+	rounds, _ := doc["rounds"].(map[string]interface{})
+	winners, _ := doc["winners"].(map[string]interface{})
+
+	for prizeNo, v := range winners {
+		s, ok := v.([]interface{})
+		if !ok {
+			continue
+		}
+
+		prizeRounds, _ := rounds[prizeNo].(map[string]interface{})
+		for i, w := range s {
+			winner, ok := w.(map[string]interface{})
+			if !ok {
+				return ErrSaveData
+			}
+
+			if _, ok := winner["round"]; !ok {
+				ID, _ := winner["id"].(string)
+				if round, ok := prizeRounds[ID]; ok {
+					winner["round"] = round
+				}
+			}
+
+			if _, ok := winner["position"]; !ok {
+				winner["position"] = i + 1
+			}
+		}
+	}
+
+	delete(doc, "rounds")
+	return nil
+}
+
+// saveVersion returns the version of the JSON object of the saved data.
+func saveVersion(doc map[string]interface{}) (int, error) {
+	v, ok := doc["version"]
+	if !ok {
+		return 0, nil
+	}
+
+	f, ok := v.(float64)
+	if !ok || f != float64(int(f)) || f < 0 {
+		return 0, ErrSaveData
+	}
+
+	return int(f), nil
+}
+
+// migrate upgrades the JSON object of the saved data to SaveVersion step by
+// step. It returns the version before migration.
+func migrate(doc map[string]interface{}) (int, error) {
+	version, err := saveVersion(doc)
+	if err != nil {
+		return 0, err
+	}
+
+	if version > SaveVersion {
+		return version, ErrSaveVersion
+	}
+
+	for v := version; v < SaveVersion; v++ {
+		m, ok := migrations[v]
+		if !ok {
+			return version, ErrSaveVersion
+		}
+
+		if err := m(doc); err != nil {
+			return version, err
+		}
+		doc["version"] = v + 1
+	}
+
+	return version, nil
+}
+
+// DecodeSaveData decodes the saved data and migrates the data of old versions
+// to SaveVersion.
+// Migrated data keeps the signature of the original data, and VerifySaveData
+// verifies it over the original data. The data is signed in the current
+// format when it's saved again. Use UpgradeSaveFile to upgrade and re-sign
+// signed data files.
+func DecodeSaveData(r io.Reader) (SaveData, error) {
+	data := SaveData{}
+
+	buf, err := ioutil.ReadAll(r)
+	if err != nil {
+		return data, err
+	}
+
+	doc := make(map[string]interface{})
+	if err := json.Unmarshal(buf, &doc); err != nil {
+		return data, err
+	}
+
+	version, err := migrate(doc)
+	if err != nil {
+		return data, err
+	}
+
+	var legacy []byte
+	if version < SaveVersion {
+		if _, ok := doc["signature"]; ok {
+			if legacy, err = legacySignedMessage(buf); err != nil {
+				return data, err
+			}
+		}
+
+		if buf, err = json.Marshal(doc); err != nil {
+			return data, err
+		}
+	}
+
+	if err := json.Unmarshal(buf, &data); err != nil {
+		return data, err
+	}
+	data.legacy = legacy
+
+	return data, nil
+}
+
+// UpgradeSaveFile upgrades the saved data file of an old version to
+// SaveVersion in place. It returns the version before upgrade.
+// The file is kept as a backup named "<file>.v<version>.bak" before it's
+// replaced.
+// If signer is not nil, the file must be signed by the signer. The signature
+// is verified before upgrade, and the upgraded data is signed by the signer.
+func UpgradeSaveFile(file string, signer Signer) (int, error) {
+	buf, err := ioutil.ReadFile(file)
+	if err != nil {
+		return 0, err
+	}
+
+	doc := make(map[string]interface{})
+	if err := json.Unmarshal(buf, &doc); err != nil {
+		return 0, err
+	}
+
+	version, err := saveVersion(doc)
+	if err != nil {
+		return version, err
+	}
+
+	if version == SaveVersion {
+		return version, nil
+	}
+
+	data, err := DecodeSaveData(bytes.NewReader(buf))
+	if err != nil {
+		return version, err
+	}
+
+	if signer != nil {
+		if err := VerifySaveData(data, signer); err != nil {
+			return version, err
+		}
+	}
+
+	if fmt.Sprintf("%X", computeWinnersHash(data.Winners)) != data.Checksum {
+		return version, ErrChecksum
+	}
+
+	if signer != nil {
+		if err := SignSaveData(&data, signer); err != nil {
+			return version, err
+		}
+	}
+
+	backupFile := fmt.Sprintf("%v.v%v.bak", file, version)
+	if err := copyFile(file, backupFile); err != nil {
+		return version, err
+	}
+
+	err = writeFileAtomic(file, func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "    ")
+		return enc.Encode(&data)
+	})
+
+	return version, err
+}
+
+// writeFileAtomic writes the file by writing a temporary file in the same
+// dir, syncing it and renaming it to the file.
+func writeFileAtomic(file string, write func(w io.Writer) error) error {
+	dir := filepath.Dir(file)
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(file)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), file); err != nil {
+		return err
+	}
+
+	return syncDir(dir)
+}
@@ -0,0 +1,253 @@
+package lottery_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/northbright/lottery-go/lottery"
+)
+
+func TestDecodeSaveData(t *testing.T) {
+	l := newSeededLottery(t, "migrate")
+	if _, err := l.Draw(3); err != nil {
+		t.Fatalf("Draw() error: %v", err)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := l.Save(buf); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+
+	data, err := lottery.DecodeSaveData(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("DecodeSaveData() error: %v", err)
+	}
+	if data.Version != lottery.SaveVersion {
+		t.Errorf("version = %v, want %v", data.Version, lottery.SaveVersion)
+	}
+
+	// Data of newer versions is not supported.
+	doc := strings.Replace(buf.String(), `"version": 1`, `"version": 100`, 1)
+	if _, err := lottery.DecodeSaveData(strings.NewReader(doc)); err != lottery.ErrSaveVersion {
+		t.Errorf("DecodeSaveData() = %v, want %v", err, lottery.ErrSaveVersion)
+	}
+}
+
+// saveDataV0 is the save format of version 0 signed by the versions before
+// SaveVersion was added. The signature is over the JSON of the other fields
+// in this order. The rounds of the winners are saved in a separated map.
+type saveDataV0 struct {
+	Name         string                         `json:"name"`
+	Prizes       map[int]lottery.Prize          `json:"prizes"`
+	Blacklists   map[int]lottery.Blacklist      `json:"blacklists"`
+	Participants map[string]lottery.Participant `json:"participants"`
+	Winners      map[int][]lottery.Winner       `json:"winners"`
+	DrawRecords  map[int][]lottery.DrawRecord   `json:"draw_records,omitempty"`
+	Rounds       map[int]map[string]int         `json:"rounds,omitempty"`
+	LastUpdated  string                         `json:"last_updated"`
+	Checksum     string                         `json:"checksum"`
+	Signature    *lottery.Signature             `json:"signature,omitempty"`
+}
+
+// marshalV0 returns the JSON of the data in the format of version 0.
+func marshalV0(t *testing.T, data lottery.SaveData, signer lottery.Signer) []byte {
+	v0 := saveDataV0{
+		data.Name,
+		data.Prizes,
+		data.Blacklists,
+		data.Participants,
+		make(map[int][]lottery.Winner),
+		data.DrawRecords,
+		make(map[int]map[string]int),
+		data.LastUpdated,
+		data.Checksum,
+		nil,
+	}
+	for prizeNo, winners := range data.Winners {
+		v0.Rounds[prizeNo] = make(map[string]int)
+		for _, w := range winners {
+			v0.Rounds[prizeNo][w.ID] = w.Round
+			w.Round = 0
+			w.Position = 0
+			v0.Winners[prizeNo] = append(v0.Winners[prizeNo], w)
+		}
+	}
+
+	if signer != nil {
+		msg, err := json.Marshal(&v0)
+		if err != nil {
+			t.Fatalf("Marshal() error: %v", err)
+		}
+		sig, err := signer.Sign(msg)
+		if err != nil {
+			t.Fatalf("Sign() error: %v", err)
+		}
+		v0.Signature = &lottery.Signature{Alg: signer.Alg(), Value: base64.StdEncoding.EncodeToString(sig)}
+	}
+
+	buf, err := json.MarshalIndent(&v0, "", "    ")
+	if err != nil {
+		t.Fatalf("MarshalIndent() error: %v", err)
+	}
+	return buf
+}
+
+// writeV0File writes the data as a data file of version 0.
+func writeV0File(t *testing.T, file string, data lottery.SaveData, signer lottery.Signer) {
+	if err := os.WriteFile(file, marshalV0(t, data, signer), 0644); err != nil {
+		t.Fatalf("WriteFile() error: %v", err)
+	}
+}
+
+// assertRounds checks if the winners have the same rounds and positions.
+func assertRounds(t *testing.T, got, want []lottery.Winner) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Fatalf("got %v winners, want %v", len(got), len(want))
+	}
+	for i, w := range want {
+		if got[i].ID != w.ID || got[i].Round != w.Round || got[i].Position != w.Position {
+			t.Errorf("winner %v = %+v, want %+v", i, got[i], w)
+		}
+	}
+}
+
+func TestLoadSignedV0File(t *testing.T) {
+	signer, _ := lottery.NewHMACSigner([]byte("secret"))
+	l := newSignedLottery(t, signer)
+
+	buf := &bytes.Buffer{}
+	if err := l.Save(buf); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+	data, err := lottery.DecodeSaveData(buf)
+	if err != nil {
+		t.Fatalf("DecodeSaveData() error: %v", err)
+	}
+
+	// The legacy signature is verified over the original data.
+	v0 := marshalV0(t, data, signer)
+	loaded := lottery.New("signed", lottery.WithSigner(signer))
+	if err := loaded.Load(bytes.NewReader(v0)); err != nil {
+		t.Fatalf("Load() signed data of version 0 error: %v", err)
+	}
+	assertRounds(t, loaded.Winners(3), l.Winners(3))
+
+	// Saved data is signed in the current format.
+	buf.Reset()
+	if err := loaded.Save(buf); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+	if err := lottery.New("signed", lottery.WithSigner(signer)).Load(buf); err != nil {
+		t.Errorf("Load() re-signed data error: %v", err)
+	}
+
+	// Tampered data is not loaded.
+	tampered := bytes.Replace(v0, []byte(`"name": "signed"`), []byte(`"name": "Mallory"`), 1)
+	if err := lottery.New("signed", lottery.WithSigner(signer)).Load(bytes.NewReader(tampered)); err != lottery.ErrSignature {
+		t.Errorf("Load() tampered data = %v, want %v", err, lottery.ErrSignature)
+	}
+
+	// Unsigned data is not loaded.
+	unsigned := marshalV0(t, data, nil)
+	if err := lottery.New("signed", lottery.WithSigner(signer)).Load(bytes.NewReader(unsigned)); err != lottery.ErrUnsigned {
+		t.Errorf("Load() unsigned data = %v, want %v", err, lottery.ErrUnsigned)
+	}
+}
+
+func TestReplayV0Snapshot(t *testing.T) {
+	l := newSeededLottery(t, "migrate")
+	if _, err := l.Draw(3); err != nil {
+		t.Fatalf("Draw() error: %v", err)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := l.Save(buf); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+	data, err := lottery.DecodeSaveData(buf)
+	if err != nil {
+		t.Fatalf("DecodeSaveData() error: %v", err)
+	}
+
+	// The journal of version 0 has the snapshot of version 0.
+	snapshot := &bytes.Buffer{}
+	if err := json.Compact(snapshot, marshalV0(t, data, nil)); err != nil {
+		t.Fatalf("Compact() error: %v", err)
+	}
+	journal := `{"seq":1,"type":"snapshot","time":"2020-01-01 00:00:00","data":` + snapshot.String() + "}\n"
+
+	replayed := lottery.New("migrate")
+	if err := replayed.Replay(strings.NewReader(journal)); err != nil {
+		t.Fatalf("Replay() error: %v", err)
+	}
+	assertRounds(t, replayed.Winners(3), l.Winners(3))
+}
+
+func TestUpgradeSaveFile(t *testing.T) {
+	signer, _ := lottery.NewHMACSigner([]byte("secret"))
+	l := newSignedLottery(t, signer)
+
+	buf := &bytes.Buffer{}
+	if err := l.Save(buf); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+	data, err := lottery.DecodeSaveData(buf)
+	if err != nil {
+		t.Fatalf("DecodeSaveData() error: %v", err)
+	}
+
+	file := filepath.Join(t.TempDir(), "signed.json")
+
+	// Unsigned data is not upgraded and signed.
+	writeV0File(t, file, data, nil)
+	if _, err := lottery.UpgradeSaveFile(file, signer); err != lottery.ErrUnsigned {
+		t.Errorf("UpgradeSaveFile() unsigned data = %v, want %v", err, lottery.ErrUnsigned)
+	}
+	if _, err := os.Stat(file + ".v0.bak"); !os.IsNotExist(err) {
+		t.Errorf("backup file of unsigned data exists: %v", err)
+	}
+
+	writeV0File(t, file, data, signer)
+	version, err := lottery.UpgradeSaveFile(file, signer)
+	if err != nil {
+		t.Fatalf("UpgradeSaveFile() error: %v", err)
+	}
+	if version != 0 {
+		t.Errorf("UpgradeSaveFile() version = %v, want 0", version)
+	}
+
+	if _, err := os.Stat(file + ".v0.bak"); err != nil {
+		t.Errorf("backup file error: %v", err)
+	}
+
+	f, _ := os.Open(file)
+	upgraded := lottery.New("signed", lottery.WithSigner(signer))
+	err = upgraded.Load(f)
+	f.Close()
+	if err != nil {
+		t.Fatalf("Load() upgraded data error: %v", err)
+	}
+	assertRounds(t, upgraded.Winners(3), l.Winners(3))
+
+	// Upgraded files are not changed.
+	if version, err := lottery.UpgradeSaveFile(file, signer); err != nil || version != lottery.SaveVersion {
+		t.Errorf("UpgradeSaveFile() = %v, %v, want %v", version, err, lottery.SaveVersion)
+	}
+
+	// Tampered old data is not upgraded.
+	tampered := bytes.Replace(marshalV0(t, data, signer), []byte(`"name": "signed"`), []byte(`"name": "Mallory"`), 1)
+	if err := os.WriteFile(file, tampered, 0644); err != nil {
+		t.Fatalf("WriteFile() error: %v", err)
+	}
+
+	if _, err := lottery.UpgradeSaveFile(file, signer); err != lottery.ErrSignature {
+		t.Errorf("UpgradeSaveFile() tampered data = %v, want %v", err, lottery.ErrSignature)
+	}
+}
@@ -0,0 +1,145 @@
+package lottery
+
+import (
+	"fmt"
+	"time"
+)
+
+// Revocation is a revoked winner of a prize.
+type Revocation struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Reason is the reason code of the revocation.
+	Reason string `json:"reason,omitempty"`
+	// Policy is the revoke policy applied to the revoked winner.
+	// It should be one of the Revoke* policy consts.
+	Policy    string `json:"policy"`
+	RevokedAt string `json:"revoked_at"`
+}
+
+const (
+	// RevokeReturn returns the revoked winner to the pool.
+	// The participant is available for the prize again.
+	RevokeReturn = "return"
+	// RevokeExcludePrize excludes the revoked winner from the prize only.
+	RevokeExcludePrize = "exclude_prize"
+	// RevokeExcludeLottery excludes the revoked winner from all prizes.
+	RevokeExcludeLottery = "exclude_lottery"
+
+	// ReasonAbsent means the winner is absent.
+	ReasonAbsent = "absent"
+	// ReasonIneligible means the winner is not eligible(e.g. not an employee).
+	ReasonIneligible = "ineligible"
+	// ReasonMistake means the winner was drawn by mistake(e.g. wrong prize).
+	ReasonMistake = "mistake"
+)
+
+var (
+	ErrRevokePolicy = fmt.Errorf("incorrect revoke policy")
+
+	// defaultRevokePolicies are the revoke policies of the predefined reasons.
+	// Other reasons use RevokeReturn by default.
+	defaultRevokePolicies = map[string]string{
+		ReasonAbsent:     RevokeExcludeLottery,
+		ReasonIneligible: RevokeExcludeLottery,
+		ReasonMistake:    RevokeReturn,
+	}
+)
+
+// SetRevokePolicy sets the policy of revoked winners with the reason code.
+// The policy should be one of the Revoke* policy consts.
+// It does not change the policies of previous revocations.
+func (l *Lottery) SetRevokePolicy(reason string, policy string) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	switch policy {
+	case RevokeReturn, RevokeExcludePrize, RevokeExcludeLottery:
+	default:
+		return ErrRevokePolicy
+	}
+
+	if err := l.record(Event{Type: EventSetRevokePolicy, Reason: reason, Policy: policy, Meta: l.meta}); err != nil {
+		return err
+	}
+
+	l.revokePolicies[reason] = policy
+	return nil
+}
+
+// RevokePolicy returns the policy of revoked winners with the reason code.
+func (l *Lottery) RevokePolicy(reason string) string {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return l.revokePolicy(reason)
+}
+
+func (l *Lottery) revokePolicy(reason string) string {
+	if policy, ok := l.revokePolicies[reason]; ok {
+		return policy
+	}
+
+	if policy, ok := defaultRevokePolicies[reason]; ok {
+		return policy
+	}
+
+	return RevokeReturn
+}
+
+// Revocations returns the revoked winners of the prize in order.
+func (l *Lottery) Revocations(prizeNo int) []Revocation {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if _, ok := l.revocations[prizeNo]; !ok {
+		return []Revocation{}
+	}
+
+	return l.revocations[prizeNo]
+}
+
+// revokeWinner stops the claim of the revoked winner of the prize and
+// records the revocation with the policy of the reason.
+// The caller removes the winner from the winners.
+func (l *Lottery) revokeWinner(prizeNo int, winner Winner, reason string) {
+	l.stopClaimTimer(prizeNo, winner.ID)
+	delete(l.claims[prizeNo], winner.ID)
+	l.recordRevocation(prizeNo, winner, reason)
+}
+
+// recordRevocation records the revoked winner of the prize.
+func (l *Lottery) recordRevocation(prizeNo int, winner Winner, reason string) {
+	r := Revocation{
+		winner.ID,
+		winner.Name,
+		reason,
+		l.revokePolicy(reason),
+		time.Now().Format("2006-01-02 15:04:05"),
+	}
+
+	l.revocations[prizeNo] = append(l.revocations[prizeNo], r)
+}
+
+// excluded reports whether the participant is excluded from the prize
+// by the policies of the revocations.
+func (l *Lottery) excluded(prizeNo int, ID string) bool {
+	for no, revocations := range l.revocations {
+		for _, r := range revocations {
+			if r.ID != ID {
+				continue
+			}
+
+			switch r.Policy {
+			case RevokeExcludeLottery:
+				return true
+			case RevokeExcludePrize:
+				if no == prizeNo {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,151 @@
+package lottery_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/northbright/lottery-go/lottery"
+)
+
+func available(l *lottery.Lottery, prizeNo int, ID string) bool {
+	for _, p := range l.AvailableParticipants(prizeNo) {
+		if p.ID == ID {
+			return true
+		}
+	}
+	return false
+}
+
+func TestRevokePolicies(t *testing.T) {
+	l := newSeededLottery(t, "revoke")
+
+	if err := l.SetRevokePolicy("late", lottery.RevokeExcludePrize); err != nil {
+		t.Fatalf("SetRevokePolicy() error: %v", err)
+	}
+	if err := l.SetRevokePolicy("late", "drop"); err != lottery.ErrRevokePolicy {
+		t.Errorf("SetRevokePolicy() with unknown policy = %v, want %v", err, lottery.ErrRevokePolicy)
+	}
+
+	winners, err := l.Draw(3)
+	if err != nil {
+		t.Fatalf("Draw() error: %v", err)
+	}
+
+	revoke := func(w lottery.Winner, reason string) {
+		if err := l.Revoke(3, []lottery.Participant{w.Participant}, reason); err != nil {
+			t.Fatalf("Revoke() error: %v", err)
+		}
+	}
+
+	revoke(winners[0], lottery.ReasonMistake)
+	revoke(winners[1], "late")
+	revoke(winners[2], lottery.ReasonAbsent)
+
+	tests := []struct {
+		ID          string
+		prize3      bool
+		otherPrizes bool
+	}{
+		{winners[0].ID, true, true},
+		{winners[1].ID, false, true},
+		{winners[2].ID, false, false},
+	}
+
+	for _, tt := range tests {
+		if got := available(l, 3, tt.ID); got != tt.prize3 {
+			t.Errorf("%v available for prize 3: %v, want %v", tt.ID, got, tt.prize3)
+		}
+		if got := available(l, 2, tt.ID); got != tt.otherPrizes {
+			t.Errorf("%v available for prize 2: %v, want %v", tt.ID, got, tt.otherPrizes)
+		}
+	}
+
+	revocations := l.Revocations(3)
+	if len(revocations) != 3 || revocations[2].Reason != lottery.ReasonAbsent || revocations[2].Policy != lottery.RevokeExcludeLottery {
+		t.Fatalf("Revocations() = %v", revocations)
+	}
+
+	// Revocations are kept after save and load.
+	buf := &bytes.Buffer{}
+	if err := l.Save(buf); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+
+	loaded := lottery.New("revoke")
+	if err := loaded.Load(buf); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if available(loaded, 2, winners[2].ID) {
+		t.Errorf("%v is available after load", winners[2].ID)
+	}
+	if policy := loaded.RevokePolicy("late"); policy != lottery.RevokeExcludePrize {
+		t.Errorf("RevokePolicy() after load = %v, want %v", policy, lottery.RevokeExcludePrize)
+	}
+}
+
+func TestReplace(t *testing.T) {
+	l := newSeededLottery(t, "replace")
+
+	winners, err := l.Draw(3)
+	if err != nil {
+		t.Fatalf("Draw() error: %v", err)
+	}
+
+	if _, err := l.Replace(3, []string{"no-such-winner"}, lottery.ReasonAbsent); err != lottery.ErrRevokedWinnerNotMatch {
+		t.Errorf("Replace() of a non-winner = %v, want %v", err, lottery.ErrRevokedWinnerNotMatch)
+	}
+
+	replacements, err := l.Replace(3, []string{winners[3].ID, winners[1].ID}, lottery.ReasonAbsent)
+	if err != nil {
+		t.Fatalf("Replace() error: %v", err)
+	}
+	if len(replacements) != 2 {
+		t.Fatalf("got %v replacements, want 2", len(replacements))
+	}
+
+	// Replacements are returned in the order of the positions.
+	if replacements[0].Replaces != winners[1].ID || replacements[0].Position != 2 {
+		t.Errorf("1st replacement = %+v, want to replace %v at position 2", replacements[0], winners[1].ID)
+	}
+	if replacements[1].Replaces != winners[3].ID || replacements[1].Position != 4 {
+		t.Errorf("2nd replacement = %+v, want to replace %v at position 4", replacements[1], winners[3].ID)
+	}
+
+	after := l.Winners(3)
+	if len(after) != len(winners) {
+		t.Fatalf("got %v winners after replace, want %v", len(after), len(winners))
+	}
+	for _, i := range []int{0, 2, 4} {
+		if after[i].ID != winners[i].ID {
+			t.Errorf("winner at position %v = %v, want %v", i+1, after[i].ID, winners[i].ID)
+		}
+	}
+	if n := len(l.Revocations(3)); n != 2 {
+		t.Errorf("got %v revocations, want 2", n)
+	}
+}
+
+func TestReplaceIsAtomic(t *testing.T) {
+	l := lottery.New("replace")
+	l.SetPrize(1, "Grand", 2, "")
+	if err := l.LoadParticipantsCSV(strings.NewReader("ID,Name\n1,Alice\n2,Bob\n")); err != nil {
+		t.Fatalf("LoadParticipantsCSV() error: %v", err)
+	}
+
+	winners, err := l.Draw(1)
+	if err != nil {
+		t.Fatalf("Draw() error: %v", err)
+	}
+
+	// No one else is available for the replacement.
+	if _, err := l.Replace(1, []string{winners[0].ID}, lottery.ReasonAbsent); err != lottery.ErrNoAvailableParticipants {
+		t.Fatalf("Replace() = %v, want %v", err, lottery.ErrNoAvailableParticipants)
+	}
+	if after := l.Winners(1); len(after) != 2 || after[0].ID != winners[0].ID {
+		t.Errorf("winners changed after failed replace: %v", after)
+	}
+	if n := len(l.Revocations(1)); n != 0 {
+		t.Errorf("got %v revocations after failed replace, want 0", n)
+	}
+}
@@ -0,0 +1,84 @@
+package lottery
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"math/big"
+	"sync"
+)
+
+// RNG is the source of randomness used by the draws of a lottery.
+type RNG interface {
+	// Intn returns a uniformly distributed random number in [0, n).
+	// It panics if n <= 0.
+	Intn(n int) int
+}
+
+type cryptoRNG struct{}
+
+// NewCryptoRNG returns a RNG backed by crypto/rand.
+// It's the default RNG of a lottery.
+func NewCryptoRNG() RNG {
+	return cryptoRNG{}
+}
+
+func (r cryptoRNG) Intn(n int) int {
+	if n <= 0 {
+		panic("lottery: invalid argument to Intn")
+	}
+
+	v, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		panic(err)
+	}
+	return int(v.Int64())
+}
+
+type seededRNG struct {
+	seed    []byte
+	counter uint64
+	mutex   *sync.Mutex
+}
+
+// NewSeededRNG returns a deterministic RNG seeded with the given seed.
+// The same seed always produces the same sequence, which makes it useful
+// for tests, rehearsals and verifiable draws.
+//
+// The sequence is produced by SHA-256(seed || counter) where counter is a
+// big-endian uint64 starting at 0, and numbers are taken from the first 8
+// bytes of each block with rejection sampling to avoid modulo bias.
+func NewSeededRNG(seed []byte) RNG {
+	s := make([]byte, len(seed))
+	copy(s, seed)
+
+	return &seededRNG{s, 0, &sync.Mutex{}}
+}
+
+func (r *seededRNG) next() uint64 {
+	buf := make([]byte, len(r.seed)+8)
+	copy(buf, r.seed)
+	binary.BigEndian.PutUint64(buf[len(r.seed):], r.counter)
+	r.counter++
+
+	sum := sha256.Sum256(buf)
+	return binary.BigEndian.Uint64(sum[:8])
+}
+
+func (r *seededRNG) Intn(n int) int {
+	if n <= 0 {
+		panic("lottery: invalid argument to Intn")
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	// Reject values in the incomplete last range to avoid modulo bias.
+	max := ^uint64(0) - ^uint64(0)%uint64(n)
+	for {
+		v := r.next()
+		if v < max {
+			return int(v % uint64(n))
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package lottery_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/northbright/lottery-go/lottery"
+)
+
+func newSeededLottery(t *testing.T, seed string) *lottery.Lottery {
+	l := lottery.New("seeded", lottery.WithRNG(lottery.NewSeededRNG([]byte(seed))))
+
+	if err := l.LoadParticipantsCSVFile("settings/participants.example.csv"); err != nil {
+		t.Fatalf("LoadParticipantsCSVFile() error: %v", err)
+	}
+	if err := l.LoadPrizesCSVFile("settings/prizes.example.csv"); err != nil {
+		t.Fatalf("LoadPrizesCSVFile() error: %v", err)
+	}
+	return l
+}
+
+func participants(winners []lottery.Winner) []lottery.Participant {
+	s := []lottery.Participant{}
+	for _, w := range winners {
+		s = append(s, w.Participant)
+	}
+	return s
+}
+
+func TestSeededRNGIsDeterministic(t *testing.T) {
+	a := newSeededLottery(t, "rehearsal")
+	b := newSeededLottery(t, "rehearsal")
+
+	for _, prizeNo := range []int{3, 2, 1} {
+		w1, err := a.Draw(prizeNo)
+		if err != nil {
+			t.Fatalf("Draw(%v) error: %v", prizeNo, err)
+		}
+		w2, err := b.Draw(prizeNo)
+		if err != nil {
+			t.Fatalf("Draw(%v) error: %v", prizeNo, err)
+		}
+		// Compare the participants only: operation IDs and draw times differ.
+		if !reflect.DeepEqual(participants(w1), participants(w2)) {
+			t.Errorf("prize %v: winners differ with the same seed: %v, %v", prizeNo, w1, w2)
+		}
+	}
+}
+
+func TestSeededRNGIntn(t *testing.T) {
+	rng := lottery.NewSeededRNG([]byte("range"))
+
+	for i := 0; i < 1000; i++ {
+		if v := rng.Intn(7); v < 0 || v >= 7 {
+			t.Fatalf("Intn(7) = %v, out of range", v)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package lottery
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+// Rule is an eligibility rule of a prize on an attribute of participants
+// or on the win history of previous events.
+// A participant is eligible for a prize only if all rules of the prize match.
+type Rule struct {
+	// Attribute is the name of the participant attribute.
+	Attribute string `json:"attribute,omitempty"`
+	// Op is the operator. It should be one of the Rule* consts.
+	Op string `json:"op"`
+	// Values are the values to compare with the attribute.
+	// "in" and "not_in" use all values, other operators use the first one.
+	Values []string `json:"values,omitempty"`
+	// MaxPrizeNo is used by history rules.
+	// Only wins of prizes with no <= MaxPrizeNo count. 0 means any prize.
+	MaxPrizeNo int `json:"max_prize_no,omitempty"`
+	// LastEvents is used by history rules.
+	// Only wins in the last events count. 0 means all events.
+	LastEvents int `json:"last_events,omitempty"`
+	// WeightPercent is used by history weight rules.
+	// e.g. 200 doubles the weight and 50 halves the weight.
+	WeightPercent int `json:"weight_percent,omitempty"`
+}
+
+const (
+	// RuleIn matches if the attribute equals one of the values.
+	RuleIn = "in"
+	// RuleNotIn matches if the attribute equals none of the values.
+	RuleNotIn = "not_in"
+	// RuleLT, RuleLTE, RuleGT and RuleGTE compare the attribute with the value.
+	// They compare numbers if both are numbers. Otherwise, they compare strings,
+	// which works for dates in "2006-01-02" format.
+	RuleLT  = "lt"
+	RuleLTE = "lte"
+	RuleGT  = "gt"
+	RuleGTE = "gte"
+	// RuleHistoryExclude excludes participants who won in the history.
+	RuleHistoryExclude = "history_exclude"
+	// RuleHistoryWeight applies the weight percent to participants who won
+	// in the history.
+	RuleHistoryWeight = "history_weight"
+	// RuleNoHistoryWeight applies the weight percent to participants who
+	// never won in the history.
+	RuleNoHistoryWeight = "no_history_weight"
+)
+
+var (
+	ErrRule = fmt.Errorf("incorrect eligibility rule")
+)
+
+// isHistory reports whether the rule is a history rule.
+func (r Rule) isHistory() bool {
+	switch r.Op {
+	case RuleHistoryExclude, RuleHistoryWeight, RuleNoHistoryWeight:
+		return true
+	default:
+		return false
+	}
+}
+
+func (r Rule) valid() bool {
+	if r.isHistory() {
+		if r.MaxPrizeNo < 0 || r.LastEvents < 0 {
+			return false
+		}
+		if r.Op != RuleHistoryExclude && r.WeightPercent < 1 {
+			return false
+		}
+		return true
+	}
+
+	if r.Attribute == "" || len(r.Values) == 0 {
+		return false
+	}
+
+	switch r.Op {
+	case RuleIn, RuleNotIn, RuleLT, RuleLTE, RuleGT, RuleGTE:
+		return true
+	default:
+		return false
+	}
+}
+
+// compareValues returns -1, 0 or 1 if a is less than, equal to or
+// greater than b.
+func compareValues(a, b string) int {
+	x, errX := strconv.ParseFloat(a, 64)
+	y, errY := strconv.ParseFloat(b, 64)
+	if errX == nil && errY == nil {
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		default:
+			return 0
+		}
+	}
+
+	return strings.Compare(a, b)
+}
+
+// Match reports whether the participant matches the rule.
+// A missing attribute is treated as an empty string,
+// which never matches the comparison operators.
+// History rules always match here, they're applied with the history
+// of the lottery.
+func (r Rule) Match(p Participant) bool {
+	if r.isHistory() {
+		return true
+	}
+
+	v := p.Attributes[r.Attribute]
+
+	switch r.Op {
+	case RuleIn, RuleNotIn:
+		found := false
+		for _, value := range r.Values {
+			if v == value {
+				found = true
+				break
+			}
+		}
+		return found == (r.Op == RuleIn)
+	case RuleLT, RuleLTE, RuleGT, RuleGTE:
+		if v == "" {
+			return false
+		}
+
+		c := compareValues(v, r.Values[0])
+		switch r.Op {
+		case RuleLT:
+			return c < 0
+		case RuleLTE:
+			return c <= 0
+		case RuleGT:
+			return c > 0
+		default:
+			return c >= 0
+		}
+	default:
+		return false
+	}
+}
+
+func matchRules(rules []Rule, p Participant) bool {
+	for _, rule := range rules {
+		if !rule.Match(p) {
+			return false
+		}
+	}
+	return true
+}
+
+// SetPrizeRules sets the eligibility rules of the prize.
+// Only participants matching all rules are available for the prize.
+func (l *Lottery) SetPrizeRules(prizeNo int, rules []Rule) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	prize, ok := l.prizes[prizeNo]
+	if !ok {
+		return ErrPrizeNo
+	}
+
+	for _, rule := range rules {
+		if !rule.valid() {
+			return ErrRule
+		}
+	}
+
+	prize.Rules = rules
+	if err := l.record(Event{Type: EventSetPrizeRules, Prize: &prize, Meta: l.meta}); err != nil {
+		return err
+	}
+
+	l.prizes[prizeNo] = prize
+	return nil
+}
+
+// LoadPrizeRulesJSONFile loads the eligibility rules of prizes from
+// the JSON file. The JSON is an object which maps prize no to rules.
+// All rules are validated before they're set, so nothing is changed if
+// any prize no or rule is incorrect.
+func (l *Lottery) LoadPrizeRulesJSONFile(f string) error {
+	buf, err := ioutil.ReadFile(f)
+	if err != nil {
+		return err
+	}
+
+	m := make(map[int][]Rule)
+	if err := json.Unmarshal(buf, &m); err != nil {
+		return err
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	for prizeNo, rules := range m {
+		if _, ok := l.prizes[prizeNo]; !ok {
+			return ErrPrizeNo
+		}
+
+		for _, rule := range rules {
+			if !rule.valid() {
+				return ErrRule
+			}
+		}
+	}
+
+	prizes := make(map[int]Prize)
+	for prizeNo, rules := range m {
+		prize := l.prizes[prizeNo]
+		prize.Rules = rules
+		prizes[prizeNo] = prize
+	}
+
+	if err := l.record(Event{Type: EventLoadPrizeRules, Prizes: prizes, Meta: l.meta}); err != nil {
+		return err
+	}
+
+	for prizeNo, prize := range prizes {
+		l.prizes[prizeNo] = prize
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package lottery_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/northbright/lottery-go/lottery"
+)
+
+func TestPrizeRules(t *testing.T) {
+	csv := `ID,Name,Weight,Office,Type,Hired
+1,Alice,,Shanghai,Full-time,2015-03-01
+2,Bob,2,Beijing,Full-time,2019-07-15
+3,Carol,,Shanghai,Intern,2021-09-01
+4,Dave,,Shanghai,Full-time,2020-01-01
+`
+	l := lottery.New("rules")
+	if err := l.LoadParticipantsCSV(strings.NewReader(csv)); err != nil {
+		t.Fatalf("LoadParticipantsCSV() error: %v", err)
+	}
+	l.SetPrize(1, "1st prize", 10, "")
+
+	rules := []lottery.Rule{
+		{Attribute: "Office", Op: lottery.RuleIn, Values: []string{"Shanghai"}},
+		{Attribute: "Type", Op: lottery.RuleNotIn, Values: []string{"Intern"}},
+		{Attribute: "Hired", Op: lottery.RuleLT, Values: []string{"2020-01-01"}},
+	}
+	if err := l.SetPrizeRules(1, rules); err != nil {
+		t.Fatalf("SetPrizeRules() error: %v", err)
+	}
+
+	available := l.AvailableParticipants(1)
+	if len(available) != 1 || available[0].ID != "1" {
+		t.Errorf("AvailableParticipants() = %v, want Alice only", available)
+	}
+
+	if err := l.SetPrizeRules(1, []lottery.Rule{{Attribute: "Office", Op: "like", Values: []string{"S"}}}); err != lottery.ErrRule {
+		t.Errorf("SetPrizeRules() with unknown op = %v, want %v", err, lottery.ErrRule)
+	}
+}
+
+func TestLoadPrizeRulesJSONFile(t *testing.T) {
+	l := lottery.New("rules")
+	l.SetPrize(1, "1st prize", 1, "")
+	l.SetPrize(2, "2nd prize", 2, "")
+
+	file := filepath.Join(t.TempDir(), "rules.json")
+	write := func(s string) {
+		if err := os.WriteFile(file, []byte(s), 0644); err != nil {
+			t.Fatalf("WriteFile() error: %v", err)
+		}
+	}
+
+	// Nothing is changed if any rule is incorrect.
+	write(`{
+    "1": [{"attribute": "Office", "op": "in", "values": ["Shanghai"]}],
+    "2": [{"attribute": "Office", "op": "like", "values": ["S"]}]
+}`)
+	if err := l.LoadPrizeRulesJSONFile(file); err != lottery.ErrRule {
+		t.Errorf("LoadPrizeRulesJSONFile() = %v, want %v", err, lottery.ErrRule)
+	}
+	if rules := l.Prize(1).Rules; len(rules) != 0 {
+		t.Errorf("rules of prize 1 = %v, want none", rules)
+	}
+
+	write(`{
+    "1": [{"attribute": "Office", "op": "in", "values": ["Shanghai"]}],
+    "2": [{"attribute": "Type", "op": "not_in", "values": ["Intern"]}]
+}`)
+	if err := l.LoadPrizeRulesJSONFile(file); err != nil {
+		t.Fatalf("LoadPrizeRulesJSONFile() error: %v", err)
+	}
+	if len(l.Prize(1).Rules) != 1 || len(l.Prize(2).Rules) != 1 {
+		t.Errorf("rules = %v, %v, want 1 rule of each prize", l.Prize(1).Rules, l.Prize(2).Rules)
+	}
+}
@@ -0,0 +1,228 @@
+package lottery
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+)
+
+// Signature is the signature of the saved data.
+type Signature struct {
+	// Alg is the signing algorithm: SignHMACSHA256 or SignEd25519.
+	Alg string `json:"alg"`
+	// Value is the base64 encoded signature.
+	Value string `json:"value"`
+}
+
+// Signer signs and verifies the saved data.
+type Signer interface {
+	// Alg returns the signing algorithm.
+	Alg() string
+	// Sign returns the signature of the message.
+	Sign(msg []byte) ([]byte, error)
+	// Verify reports whether the signature of the message is valid.
+	Verify(msg []byte, sig []byte) bool
+}
+
+type hmacSigner struct {
+	key []byte
+}
+
+type ed25519Signer struct {
+	privateKey ed25519.PrivateKey
+	publicKey  ed25519.PublicKey
+}
+
+const (
+	// SignHMACSHA256 is the HMAC-SHA256 signing algorithm.
+	SignHMACSHA256 = "hmac-sha256"
+	// SignEd25519 is the Ed25519 signing algorithm.
+	SignEd25519 = "ed25519"
+)
+
+var (
+	ErrUnsigned     = fmt.Errorf("data is not signed")
+	ErrSignature    = fmt.Errorf("incorrect signature")
+	ErrSigningKey   = fmt.Errorf("incorrect signing key")
+	ErrNoSigningKey = fmt.Errorf("no private key to sign")
+)
+
+// NewHMACSigner returns a HMAC-SHA256 signer with the secret key.
+func NewHMACSigner(key []byte) (Signer, error) {
+	if len(key) == 0 {
+		return nil, ErrSigningKey
+	}
+
+	return &hmacSigner{append([]byte{}, key...)}, nil
+}
+
+func (s *hmacSigner) Alg() string {
+	return SignHMACSHA256
+}
+
+func (s *hmacSigner) Sign(msg []byte) ([]byte, error) {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write(msg)
+	return mac.Sum(nil), nil
+}
+
+func (s *hmacSigner) Verify(msg []byte, sig []byte) bool {
+	expected, _ := s.Sign(msg)
+	return hmac.Equal(expected, sig)
+}
+
+// NewEd25519Signer returns an Ed25519 signer with the private key.
+func NewEd25519Signer(privateKey ed25519.PrivateKey) (Signer, error) {
+	if len(privateKey) != ed25519.PrivateKeySize {
+		return nil, ErrSigningKey
+	}
+
+	publicKey := privateKey.Public().(ed25519.PublicKey)
+	return &ed25519Signer{privateKey, publicKey}, nil
+}
+
+// NewEd25519Verifier returns an Ed25519 signer with the public key only.
+// It verifies the signatures but can not sign(e.g. for auditors).
+func NewEd25519Verifier(publicKey ed25519.PublicKey) (Signer, error) {
+	if len(publicKey) != ed25519.PublicKeySize {
+		return nil, ErrSigningKey
+	}
+
+	return &ed25519Signer{nil, publicKey}, nil
+}
+
+func (s *ed25519Signer) Alg() string {
+	return SignEd25519
+}
+
+func (s *ed25519Signer) Sign(msg []byte) ([]byte, error) {
+	if s.privateKey == nil {
+		return nil, ErrNoSigningKey
+	}
+
+	return ed25519.Sign(s.privateKey, msg), nil
+}
+
+func (s *ed25519Signer) Verify(msg []byte, sig []byte) bool {
+	return ed25519.Verify(s.publicKey, msg, sig)
+}
+
+// WithSigner sets the signer of the saved data.
+// The saved data is signed over all fields, and the data to load must be
+// signed by the signer.
+// Without a signer, the data is not signed and the signatures of the data
+// to load are not verified.
+func WithSigner(s Signer) Option {
+	return func(l *Lottery) {
+		l.signer = s
+	}
+}
+
+// signedMessage returns the message to sign of the data:
+// the JSON of the data without signature.
+func signedMessage(data SaveData) ([]byte, error) {
+	data.Signature = nil
+	return json.Marshal(&data)
+}
+
+// legacySignedMessage returns the message signed by an old version of the
+// JSON object of the saved data: the compacted JSON object without the
+// signature, in the original order of the fields.
+func legacySignedMessage(buf []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(buf))
+	if t, err := dec.Token(); err != nil || t != json.Delim('{') {
+		return nil, ErrSaveData
+	}
+
+	msg := &bytes.Buffer{}
+	msg.WriteByte('{')
+	for n := 0; dec.More(); {
+		t, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		key, ok := t.(string)
+		if !ok {
+			return nil, ErrSaveData
+		}
+
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+
+		if key == "signature" {
+			continue
+		}
+
+		if n > 0 {
+			msg.WriteByte(',')
+		}
+		k, _ := json.Marshal(key)
+		msg.Write(k)
+		msg.WriteByte(':')
+		if err := json.Compact(msg, value); err != nil {
+			return nil, err
+		}
+		n++
+	}
+	msg.WriteByte('}')
+
+	return msg.Bytes(), nil
+}
+
+// SignSaveData signs all fields of the data with the signer.
+// Data migrated from an old version is signed in the current format.
+func SignSaveData(data *SaveData, s Signer) error {
+	data.legacy = nil
+	msg, err := signedMessage(*data)
+	if err != nil {
+		return err
+	}
+
+	sig, err := s.Sign(msg)
+	if err != nil {
+		return err
+	}
+
+	data.Signature = &Signature{s.Alg(), base64.StdEncoding.EncodeToString(sig)}
+	return nil
+}
+
+// VerifySaveData verifies the signature of the data with the signer.
+// It returns ErrUnsigned if the data is not signed, or ErrSignature if the
+// signature is incorrect(e.g. the data is modified).
+// The signature of data migrated from an old version is verified over the
+// original data.
+func VerifySaveData(data SaveData, s Signer) error {
+	if data.Signature == nil {
+		return ErrUnsigned
+	}
+
+	if data.Signature.Alg != s.Alg() {
+		return ErrSignature
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(data.Signature.Value)
+	if err != nil {
+		return ErrSignature
+	}
+
+	msg := data.legacy
+	if msg == nil {
+		if msg, err = signedMessage(data); err != nil {
+			return err
+		}
+	}
+
+	if !s.Verify(msg, sig) {
+		return ErrSignature
+	}
+
+	return nil
+}
@@ -0,0 +1,118 @@
+package lottery_test
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/northbright/lottery-go/lottery"
+)
+
+// newSignedLottery returns a lottery with drawn winners signed by the signer.
+func newSignedLottery(t *testing.T, signer lottery.Signer) *lottery.Lottery {
+	l := lottery.New("signed", lottery.WithSigner(signer))
+	if err := l.LoadParticipantsCSVFile("settings/participants.example.csv"); err != nil {
+		t.Fatalf("LoadParticipantsCSVFile() error: %v", err)
+	}
+	if err := l.LoadPrizesCSVFile("settings/prizes.example.csv"); err != nil {
+		t.Fatalf("LoadPrizesCSVFile() error: %v", err)
+	}
+	if _, err := l.Draw(3); err != nil {
+		t.Fatalf("Draw() error: %v", err)
+	}
+	return l
+}
+
+func TestSignedSaveData(t *testing.T) {
+	hmacSigner, err := lottery.NewHMACSigner([]byte("secret"))
+	if err != nil {
+		t.Fatalf("NewHMACSigner() error: %v", err)
+	}
+
+	_, privateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("GenerateKey() error: %v", err)
+	}
+	ed25519Signer, err := lottery.NewEd25519Signer(privateKey)
+	if err != nil {
+		t.Fatalf("NewEd25519Signer() error: %v", err)
+	}
+	ed25519Verifier, err := lottery.NewEd25519Verifier(privateKey.Public().(ed25519.PublicKey))
+	if err != nil {
+		t.Fatalf("NewEd25519Verifier() error: %v", err)
+	}
+
+	for _, c := range []struct {
+		signer   lottery.Signer
+		verifier lottery.Signer
+	}{
+		{hmacSigner, hmacSigner},
+		{ed25519Signer, ed25519Verifier},
+	} {
+		l := newSignedLottery(t, c.signer)
+		buf := &bytes.Buffer{}
+		if err := l.Save(buf); err != nil {
+			t.Fatalf("Save() error: %v", err)
+		}
+
+		loaded := lottery.New("signed", lottery.WithSigner(c.verifier))
+		if err := loaded.Load(bytes.NewReader(buf.Bytes())); err != nil {
+			t.Fatalf("%v: Load() error: %v", c.signer.Alg(), err)
+		}
+		if !reflect.DeepEqual(loaded.AllWinners(), l.AllWinners()) {
+			t.Errorf("%v: loaded winners = %v, want %v", c.signer.Alg(), loaded.AllWinners(), l.AllWinners())
+		}
+
+		// Modify a participant: the checksum of winners is still correct.
+		data := lottery.SaveData{}
+		if err := json.Unmarshal(buf.Bytes(), &data); err != nil {
+			t.Fatalf("Unmarshal() error: %v", err)
+		}
+		for ID, p := range data.Participants {
+			p.Name = "Mallory"
+			data.Participants[ID] = p
+			break
+		}
+		tampered, _ := json.Marshal(&data)
+		if err := loaded.Load(bytes.NewReader(tampered)); err != lottery.ErrSignature {
+			t.Errorf("%v: Load() tampered data = %v, want %v", c.signer.Alg(), err, lottery.ErrSignature)
+		}
+	}
+
+	// The verifier can not sign.
+	if err := lottery.New("verifier", lottery.WithSigner(ed25519Verifier)).Save(&bytes.Buffer{}); err != lottery.ErrNoSigningKey {
+		t.Errorf("Save() with verifier = %v, want %v", err, lottery.ErrNoSigningKey)
+	}
+
+	// Data signed by another key.
+	buf := &bytes.Buffer{}
+	if err := newSignedLottery(t, hmacSigner).Save(buf); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+	otherSigner, _ := lottery.NewHMACSigner([]byte("other secret"))
+	if err := lottery.New("signed", lottery.WithSigner(otherSigner)).Load(buf); err != lottery.ErrSignature {
+		t.Errorf("Load() with other key = %v, want %v", err, lottery.ErrSignature)
+	}
+}
+
+func TestUnsignedSaveData(t *testing.T) {
+	l := newSeededLottery(t, "unsigned")
+	buf := &bytes.Buffer{}
+	if err := l.Save(buf); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+
+	signer, _ := lottery.NewHMACSigner([]byte("secret"))
+	if err := lottery.New("seeded", lottery.WithSigner(signer)).Load(buf); err != lottery.ErrUnsigned {
+		t.Errorf("Load() unsigned data = %v, want %v", err, lottery.ErrUnsigned)
+	}
+
+	// A missing file.
+	s := lottery.NewFileStore(t.TempDir())
+	missing := lottery.New("missing", lottery.WithStore(s), lottery.WithSigner(signer))
+	if err := missing.LoadFromStore(); err != lottery.ErrNotSaved {
+		t.Errorf("LoadFromStore() missing data = %v, want %v", err, lottery.ErrNotSaved)
+	}
+}
@@ -0,0 +1,209 @@
+// Package sqlitestore implements lottery.Store with an embedded SQLite
+// database.
+//
+// The whole data of each lottery is saved in the lotteries table.
+// The prizes, participants and winners are also saved in their own tables,
+// so they can be queried with SQL. Each save is done in a transaction.
+// Deleting a lottery deletes its rows in the other tables.
+//
+// It's a separate module, so the lottery package does not depend on SQLite.
+//
+// Tables:
+//
+//	lotteries(name, data, last_updated)
+//	prizes(lottery, no, name, amount, desc)
+//	participants(lottery, id, name, weight, attributes)
+//	winners(lottery, prize_no, position, id, name, round, drawn_at, operation_id, replaces)
+package sqlitestore
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strings"
+
+	"github.com/northbright/lottery-go/lottery"
+	_ "modernc.org/sqlite"
+)
+
+// Store is a lottery.Store with a SQLite database.
+type Store struct {
+	db *sql.DB
+}
+
+var schema = []string{
+	`PRAGMA journal_mode = WAL`,
+	`CREATE TABLE IF NOT EXISTS lotteries (
+		name TEXT PRIMARY KEY,
+		data TEXT NOT NULL,
+		last_updated TEXT NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS prizes (
+		lottery TEXT NOT NULL REFERENCES lotteries(name) ON DELETE CASCADE,
+		no INTEGER NOT NULL,
+		name TEXT NOT NULL,
+		amount INTEGER NOT NULL,
+		desc TEXT NOT NULL,
+		PRIMARY KEY (lottery, no)
+	)`,
+	`CREATE TABLE IF NOT EXISTS participants (
+		lottery TEXT NOT NULL REFERENCES lotteries(name) ON DELETE CASCADE,
+		id TEXT NOT NULL,
+		name TEXT NOT NULL,
+		weight INTEGER NOT NULL,
+		attributes TEXT NOT NULL,
+		PRIMARY KEY (lottery, id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS winners (
+		lottery TEXT NOT NULL REFERENCES lotteries(name) ON DELETE CASCADE,
+		prize_no INTEGER NOT NULL,
+		position INTEGER NOT NULL,
+		id TEXT NOT NULL,
+		name TEXT NOT NULL,
+		round INTEGER NOT NULL,
+		drawn_at TEXT NOT NULL,
+		operation_id TEXT NOT NULL,
+		replaces TEXT NOT NULL,
+		PRIMARY KEY (lottery, prize_no, id)
+	)`,
+}
+
+// Open opens the SQLite database file and creates the tables if needed.
+// Foreign keys are enabled on each connection, so deleting a lottery
+// deletes its rows in the other tables. A locked database is retried for
+// up to 5 seconds.
+func Open(file string) (*Store, error) {
+	db, err := sql.Open("sqlite", file+"?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, stmt := range schema {
+		if _, err := db.Exec(stmt); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+
+	return &Store{db}, nil
+}
+
+// Close closes the database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// DB returns the database to query the lotteries with SQL.
+func (s *Store) DB() *sql.DB {
+	return s.db
+}
+
+// Save saves the data of the lottery in a transaction.
+func (s *Store) Save(data lottery.SaveData) error {
+	buf, err := json.Marshal(&data)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(
+		`INSERT INTO lotteries (name, data, last_updated) VALUES (?, ?, ?)
+		ON CONFLICT (name) DO UPDATE SET data = excluded.data, last_updated = excluded.last_updated`,
+		data.Name, string(buf), data.LastUpdated,
+	); err != nil {
+		return err
+	}
+
+	for _, table := range []string{"prizes", "participants", "winners"} {
+		if _, err := tx.Exec(`DELETE FROM `+table+` WHERE lottery = ?`, data.Name); err != nil {
+			return err
+		}
+	}
+
+	for _, prize := range data.Prizes {
+		if _, err := tx.Exec(
+			`INSERT INTO prizes (lottery, no, name, amount, desc) VALUES (?, ?, ?, ?, ?)`,
+			data.Name, prize.No, prize.Name, prize.Amount, prize.Desc,
+		); err != nil {
+			return err
+		}
+	}
+
+	for _, p := range data.Participants {
+		attributes, err := json.Marshal(p.Attributes)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(
+			`INSERT INTO participants (lottery, id, name, weight, attributes) VALUES (?, ?, ?, ?, ?)`,
+			data.Name, p.ID, p.Name, p.Weight, string(attributes),
+		); err != nil {
+			return err
+		}
+	}
+
+	for prizeNo, winners := range data.Winners {
+		for _, w := range winners {
+			if _, err := tx.Exec(
+				`INSERT INTO winners (lottery, prize_no, position, id, name, round, drawn_at, operation_id, replaces)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				data.Name, prizeNo, w.Position, w.ID, w.Name, w.Round, w.DrawnAt, w.OperationID, w.Replaces,
+			); err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Load loads the data of the lottery.
+// It returns lottery.ErrNotSaved if the lottery is not saved.
+func (s *Store) Load(name string) (lottery.SaveData, error) {
+	data := lottery.SaveData{}
+
+	var buf string
+	err := s.db.QueryRow(`SELECT data FROM lotteries WHERE name = ?`, name).Scan(&buf)
+	if err == sql.ErrNoRows {
+		return data, lottery.ErrNotSaved
+	}
+	if err != nil {
+		return data, err
+	}
+
+	return lottery.DecodeSaveData(strings.NewReader(buf))
+}
+
+// Delete deletes the lottery and its prizes, participants and winners.
+// It returns lottery.ErrNotSaved if the lottery is not saved.
+func (s *Store) Delete(name string) error {
+	res, err := s.db.Exec(`DELETE FROM lotteries WHERE name = ?`, name)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return lottery.ErrNotSaved
+	}
+
+	return nil
+}
+
+// Exists reports whether the lottery is saved.
+func (s *Store) Exists(name string) (bool, error) {
+	var n int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM lotteries WHERE name = ?`, name).Scan(&n); err != nil {
+		return false, err
+	}
+
+	return n > 0, nil
+}
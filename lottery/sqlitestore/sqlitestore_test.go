@@ -0,0 +1,120 @@
+package sqlitestore_test
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/northbright/lottery-go/lottery"
+	"github.com/northbright/lottery-go/lottery/sqlitestore"
+)
+
+func TestStore(t *testing.T) {
+	s, err := sqlitestore.Open(filepath.Join(t.TempDir(), "lottery.db"))
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	defer s.Close()
+
+	l := lottery.New("sqlite", lottery.WithStore(s))
+	if err := l.LoadParticipantsCSVFile("../settings/participants.example.csv"); err != nil {
+		t.Fatalf("LoadParticipantsCSVFile() error: %v", err)
+	}
+	if err := l.LoadPrizesCSVFile("../settings/prizes.example.csv"); err != nil {
+		t.Fatalf("LoadPrizesCSVFile() error: %v", err)
+	}
+
+	if stored, err := l.Stored(); err != nil || stored {
+		t.Errorf("Stored() = %v, %v, want false", stored, err)
+	}
+	if err := l.LoadFromStore(); err != lottery.ErrNotSaved {
+		t.Errorf("LoadFromStore() = %v, want %v", err, lottery.ErrNotSaved)
+	}
+
+	winners, err := l.Draw(3)
+	if err != nil {
+		t.Fatalf("Draw() error: %v", err)
+	}
+	if err := l.SaveToStore(); err != nil {
+		t.Fatalf("SaveToStore() error: %v", err)
+	}
+	// Save again to replace the rows.
+	if _, err := l.Draw(2); err != nil {
+		t.Fatalf("Draw() error: %v", err)
+	}
+	if err := l.SaveToStore(); err != nil {
+		t.Fatalf("SaveToStore() error: %v", err)
+	}
+
+	loaded := lottery.New("sqlite", lottery.WithStore(s))
+	if err := loaded.LoadFromStore(); err != nil {
+		t.Fatalf("LoadFromStore() error: %v", err)
+	}
+	if !reflect.DeepEqual(loaded.AllWinners(), l.AllWinners()) {
+		t.Errorf("loaded winners = %v, want %v", loaded.AllWinners(), l.AllWinners())
+	}
+
+	// Query the winners with SQL.
+	var id string
+	err = s.DB().QueryRow(
+		`SELECT id FROM winners WHERE lottery = ? AND prize_no = ? AND position = 1`, "sqlite", 3,
+	).Scan(&id)
+	if err != nil {
+		t.Fatalf("query winners error: %v", err)
+	}
+	if id != winners[0].ID {
+		t.Errorf("queried winner = %v, want %v", id, winners[0].ID)
+	}
+
+	var n int
+	if err := s.DB().QueryRow(`SELECT COUNT(*) FROM winners WHERE lottery = ?`, "sqlite").Scan(&n); err != nil {
+		t.Fatalf("count winners error: %v", err)
+	}
+	if want := len(l.Winners(3)) + len(l.Winners(2)); n != want {
+		t.Errorf("got %v winners rows, want %v", n, want)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	s, err := sqlitestore.Open(filepath.Join(t.TempDir(), "lottery.db"))
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	defer s.Close()
+
+	l := lottery.New("sqlite", lottery.WithStore(s))
+	if err := l.LoadParticipantsCSVFile("../settings/participants.example.csv"); err != nil {
+		t.Fatalf("LoadParticipantsCSVFile() error: %v", err)
+	}
+	if err := l.LoadPrizesCSVFile("../settings/prizes.example.csv"); err != nil {
+		t.Fatalf("LoadPrizesCSVFile() error: %v", err)
+	}
+	if _, err := l.Draw(3); err != nil {
+		t.Fatalf("Draw() error: %v", err)
+	}
+	if err := l.SaveToStore(); err != nil {
+		t.Fatalf("SaveToStore() error: %v", err)
+	}
+
+	if err := s.Delete("sqlite"); err != nil {
+		t.Fatalf("Delete() error: %v", err)
+	}
+	if err := s.Delete("sqlite"); err != lottery.ErrNotSaved {
+		t.Errorf("Delete() = %v, want %v", err, lottery.ErrNotSaved)
+	}
+
+	// The rows of the lottery in the other tables are deleted in cascade.
+	for _, table := range []string{"prizes", "participants", "winners"} {
+		var n int
+		if err := s.DB().QueryRow(`SELECT COUNT(*) FROM `+table+` WHERE lottery = ?`, "sqlite").Scan(&n); err != nil {
+			t.Fatalf("count %v error: %v", table, err)
+		}
+		if n != 0 {
+			t.Errorf("got %v rows in %v, want 0", n, table)
+		}
+	}
+
+	if exists, err := s.Exists("sqlite"); err != nil || exists {
+		t.Errorf("Exists() = %v, %v, want false", exists, err)
+	}
+}
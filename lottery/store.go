@@ -0,0 +1,487 @@
+package lottery
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Store saves and loads the data of lotteries by name.
+// Implementations should be safe for concurrent use.
+type Store interface {
+	// Save saves the data of the lottery named data.Name.
+	// It replaces the saved data of the lottery.
+	Save(data SaveData) error
+	// Load loads the saved data of the lottery.
+	// It returns ErrNotSaved if the lottery is not saved.
+	Load(name string) (SaveData, error)
+	// Exists reports whether the lottery is saved.
+	Exists(name string) (bool, error)
+}
+
+// BackupStore is a store which keeps backups of the saved data.
+type BackupStore interface {
+	Store
+	// Backups returns the backups of the lottery. The newest one is the first.
+	Backups(name string) ([]Backup, error)
+	// LoadBackup loads the data of the backup of the lottery.
+	LoadBackup(name string, file string) (SaveData, error)
+	// RestoreBackup verifies the backup of the lottery and restores it
+	// as the saved data.
+	RestoreBackup(name string, file string) error
+}
+
+// Backup is a backup of the saved data of a lottery.
+type Backup struct {
+	File string `json:"file"`
+	// Time is the time when the backup was made.
+	Time string `json:"time"`
+}
+
+// FileStore saves the data of each lottery as a JSON file in a dir.
+// The file name is the MD5 of the lottery name.
+// The data is written to a temporary file which is renamed to the data file
+// after it's synced, so a crash does not leave a truncated data file.
+// The replaced data files are kept as timestamped backups next to the data
+// file.
+type FileStore struct {
+	dir string
+	// backups is the max amount of backups of each lottery.
+	backups int
+	mutex   *sync.Mutex
+}
+
+const (
+	// DefaultBackups is the default max amount of backups of each lottery
+	// kept by FileStore.
+	DefaultBackups = 5
+
+	// backupTimeFormat is the time format in the backup file names.
+	// Backup file names sort in chronological order.
+	backupTimeFormat = "20060102-150405.000000000"
+)
+
+var (
+	ErrNotSaved        = fmt.Errorf("lottery is not saved")
+	ErrBackup          = fmt.Errorf("incorrect backup")
+	ErrBackupsNotFound = fmt.Errorf("store does not keep backups")
+)
+
+// NewFileStore returns a JSON file store in the dir.
+// It keeps DefaultBackups backups of each lottery.
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{dir, DefaultBackups, &sync.Mutex{}}
+}
+
+// SetBackups sets the max amount of backups of each lottery.
+// 0 means no backups.
+func (s *FileStore) SetBackups(n int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if n < 0 {
+		n = 0
+	}
+	s.backups = n
+}
+
+// WithStore sets the store used by SaveToStore and LoadFromStore.
+// The default one is the store of the workspace of the lottery.
+func WithStore(s Store) Option {
+	return func(l *Lottery) {
+		l.store = s
+	}
+}
+
+// File returns the data file of the lottery.
+func (s *FileStore) File(name string) string {
+	f := fmt.Sprintf("%X.json", md5.Sum([]byte(name)))
+	return filepath.Join(s.dir, f)
+}
+
+// Save saves the data as JSON file.
+// The replaced data file is kept as a backup.
+func (s *FileStore) Save(data SaveData) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.save(data)
+}
+
+func (s *FileStore) save(data SaveData) error {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return err
+	}
+
+	file := s.File(data.Name)
+
+	if err := s.backup(file); err != nil {
+		return err
+	}
+
+	err := writeFileAtomic(file, func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "    ")
+		return enc.Encode(&data)
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.removeOldBackups(data.Name)
+}
+
+// backup keeps the data file as a timestamped backup.
+func (s *FileStore) backup(file string) error {
+	if s.backups == 0 {
+		return nil
+	}
+
+	if _, err := os.Stat(file); os.IsNotExist(err) {
+		return nil
+	}
+
+	backupFile := fmt.Sprintf("%v.%v.bak", file, time.Now().Format(backupTimeFormat))
+
+	// Link the data file to the backup file, or copy it if the file system
+	// does not support hard links.
+	if err := os.Link(file, backupFile); err == nil {
+		return nil
+	}
+
+	return copyFile(file, backupFile)
+}
+
+// copyFile copies the file and syncs the copy.
+func copyFile(src, dst string) error {
+	r, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	w, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(w, r); err != nil {
+		w.Close()
+		return err
+	}
+
+	if err := w.Sync(); err != nil {
+		w.Close()
+		return err
+	}
+
+	return w.Close()
+}
+
+// syncDir syncs the dir so the renamed file is durable.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	return d.Sync()
+}
+
+// removeOldBackups removes the backups of the lottery exceeding the max amount.
+func (s *FileStore) removeOldBackups(name string) error {
+	backups, err := s.listBackups(name)
+	if err != nil {
+		return err
+	}
+
+	for i := s.backups; i < len(backups); i++ {
+		if err := os.Remove(filepath.Join(s.dir, backups[i].File)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// remove removes the data file and the backups of the lottery.
+func (s *FileStore) remove(name string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	backups, err := s.listBackups(name)
+	if err != nil {
+		return err
+	}
+
+	for _, backup := range backups {
+		if err := os.Remove(filepath.Join(s.dir, backup.File)); err != nil {
+			return err
+		}
+	}
+
+	if err := os.Remove(s.File(name)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+// Backups returns the backups of the lottery. The newest one is the first.
+// Backup.File is the file name in the dir of the store.
+func (s *FileStore) Backups(name string) ([]Backup, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.listBackups(name)
+}
+
+func (s *FileStore) listBackups(name string) ([]Backup, error) {
+	prefix := filepath.Base(s.File(name)) + "."
+
+	files, err := filepath.Glob(filepath.Join(s.dir, prefix+"*.bak"))
+	if err != nil {
+		return nil, err
+	}
+
+	backups := []Backup{}
+	for _, f := range files {
+		base := filepath.Base(f)
+		ts := strings.TrimSuffix(strings.TrimPrefix(base, prefix), ".bak")
+
+		tm, err := time.ParseInLocation(backupTimeFormat, ts, time.Local)
+		if err != nil {
+			continue
+		}
+
+		backups = append(backups, Backup{base, tm.Format("2006-01-02 15:04:05")})
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].File > backups[j].File
+	})
+
+	return backups, nil
+}
+
+// LoadBackup loads the data of the backup of the lottery.
+// file is the file name of the backup returned by Backups.
+func (s *FileStore) LoadBackup(name string, file string) (SaveData, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.loadBackup(name, file)
+}
+
+func (s *FileStore) loadBackup(name string, file string) (SaveData, error) {
+	_, data, err := s.readBackup(name, file)
+	return data, err
+}
+
+// readBackup reads the backup file of the lottery and decodes it.
+func (s *FileStore) readBackup(name string, file string) ([]byte, SaveData, error) {
+	backups, err := s.listBackups(name)
+	if err != nil {
+		return nil, SaveData{}, err
+	}
+
+	found := false
+	for _, backup := range backups {
+		if backup.File == file {
+			found = true
+			break
+		}
+	}
+
+	if !found {
+		return nil, SaveData{}, ErrBackup
+	}
+
+	buf, err := os.ReadFile(filepath.Join(s.dir, file))
+	if err != nil {
+		return nil, SaveData{}, err
+	}
+
+	data, err := DecodeSaveData(bytes.NewReader(buf))
+	if err != nil {
+		return nil, data, err
+	}
+
+	return buf, data, nil
+}
+
+// RestoreBackup verifies the checksum of the backup of the lottery and
+// restores it as the data file. The replaced data file is kept as a backup.
+// The backup file is copied as is, so its signature is kept.
+// It returns ErrBackup if the backup is made before the lottery is renamed.
+// Use Lottery.RestoreBackup to restore it with the current name.
+// file is the file name of the backup returned by Backups.
+func (s *FileStore) RestoreBackup(name string, file string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	buf, data, err := s.readBackup(name, file)
+	if err != nil {
+		return err
+	}
+
+	if data.Name != name {
+		return ErrBackup
+	}
+
+	if fmt.Sprintf("%X", computeWinnersHash(data.Winners)) != data.Checksum {
+		return ErrChecksum
+	}
+
+	dataFile := s.File(name)
+	if err := s.backup(dataFile); err != nil {
+		return err
+	}
+
+	err = writeFileAtomic(dataFile, func(w io.Writer) error {
+		_, err := w.Write(buf)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.removeOldBackups(name)
+}
+
+// Load loads the data from JSON file.
+func (s *FileStore) Load(name string) (SaveData, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	data, err := loadDataFile(s.File(name))
+	if os.IsNotExist(err) {
+		return data, ErrNotSaved
+	}
+
+	return data, err
+}
+
+// loadDataFile decodes the saved data from the JSON file.
+// See DecodeSaveData for more information.
+func loadDataFile(file string) (SaveData, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return SaveData{}, err
+	}
+	defer f.Close()
+
+	return DecodeSaveData(f)
+}
+
+// Exists reports whether the data file of the lottery exists.
+func (s *FileStore) Exists(name string) (bool, error) {
+	_, err := os.Stat(s.File(name))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// SaveToStore saves the data of the lottery to the store.
+func (l *Lottery) SaveToStore() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	data, err := l.saveData()
+	if err != nil {
+		return err
+	}
+
+	return l.store.Save(data)
+}
+
+// LoadFromStore loads the data of the lottery from the store.
+// It returns ErrNotSaved if the lottery is not saved.
+func (l *Lottery) LoadFromStore() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return l.loadFromStore(l.store, EventLoad)
+}
+
+// loadFromStore loads the data from the store and records the event of the
+// load or restore.
+func (l *Lottery) loadFromStore(s Store, eventType string) error {
+	data, err := s.Load(l.name)
+	if err != nil {
+		return err
+	}
+
+	if err := l.load(data); err != nil {
+		return err
+	}
+
+	return l.record(Event{Type: eventType, Meta: l.meta})
+}
+
+// Backups returns the backups of the lottery in the store.
+// The newest one is the first.
+// It returns ErrBackupsNotFound if the store does not keep backups.
+func (l *Lottery) Backups() ([]Backup, error) {
+	s, ok := l.store.(BackupStore)
+	if !ok {
+		return nil, ErrBackupsNotFound
+	}
+
+	return s.Backups(l.name)
+}
+
+// RestoreBackup verifies the checksum and the signature of the backup,
+// loads it and saves it as the data of the lottery in the store.
+// The verified data is saved in the current format with the current name and
+// signed by the signer of the lottery, so backups of old versions or made
+// before the lottery is renamed are restored as well.
+// file is the file of the backup returned by Backups.
+// It returns ErrBackupsNotFound if the store does not keep backups.
+func (l *Lottery) RestoreBackup(file string) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	s, ok := l.store.(BackupStore)
+	if !ok {
+		return ErrBackupsNotFound
+	}
+
+	data, err := s.LoadBackup(l.name, file)
+	if err != nil {
+		return err
+	}
+
+	if err := l.load(data); err != nil {
+		return err
+	}
+
+	if err := l.record(Event{Type: EventRestoreBackup, Meta: l.meta}); err != nil {
+		return err
+	}
+
+	restored, err := l.saveData()
+	if err != nil {
+		return err
+	}
+
+	return s.Save(restored)
+}
+
+// Stored reports whether the lottery is saved in the store.
+func (l *Lottery) Stored() (bool, error) {
+	return l.store.Exists(l.name)
+}
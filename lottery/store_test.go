@@ -0,0 +1,259 @@
+package lottery_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/northbright/lottery-go/lottery"
+)
+
+func TestFileStore(t *testing.T) {
+	s := lottery.NewFileStore(t.TempDir())
+	l := lottery.New("file store", lottery.WithStore(s))
+	if err := l.LoadParticipantsCSVFile("settings/participants.example.csv"); err != nil {
+		t.Fatalf("LoadParticipantsCSVFile() error: %v", err)
+	}
+	if err := l.LoadPrizesCSVFile("settings/prizes.example.csv"); err != nil {
+		t.Fatalf("LoadPrizesCSVFile() error: %v", err)
+	}
+
+	if stored, err := l.Stored(); err != nil || stored {
+		t.Errorf("Stored() = %v, %v, want false", stored, err)
+	}
+	if err := l.LoadFromStore(); err != lottery.ErrNotSaved {
+		t.Errorf("LoadFromStore() = %v, want %v", err, lottery.ErrNotSaved)
+	}
+
+	if _, err := l.Draw(3); err != nil {
+		t.Fatalf("Draw() error: %v", err)
+	}
+	if err := l.SaveToStore(); err != nil {
+		t.Fatalf("SaveToStore() error: %v", err)
+	}
+	if stored, err := l.Stored(); err != nil || !stored {
+		t.Errorf("Stored() = %v, %v, want true", stored, err)
+	}
+
+	loaded := lottery.New("file store", lottery.WithStore(s))
+	if err := loaded.LoadFromStore(); err != nil {
+		t.Fatalf("LoadFromStore() error: %v", err)
+	}
+	if !reflect.DeepEqual(loaded.AllWinners(), l.AllWinners()) {
+		t.Errorf("loaded winners = %v, want %v", loaded.AllWinners(), l.AllWinners())
+	}
+
+	h := lottery.NewHistory()
+	if err := h.ImportStore("2025", s, "file store"); err != nil {
+		t.Fatalf("ImportStore() error: %v", err)
+	}
+	for _, winner := range l.Winners(3) {
+		if wins := h.Wins(winner.ID, 0); len(wins) != 1 || wins[0].PrizeNo != 3 {
+			t.Errorf("Wins(%v) = %v, want a win of prize 3", winner.ID, wins)
+		}
+	}
+}
+
+func TestFileStoreBackups(t *testing.T) {
+	dir := t.TempDir()
+	s := lottery.NewFileStore(dir)
+	s.SetBackups(2)
+
+	l := lottery.New("backups", lottery.WithStore(s))
+	if err := l.LoadParticipantsCSVFile("settings/participants.example.csv"); err != nil {
+		t.Fatalf("LoadParticipantsCSVFile() error: %v", err)
+	}
+	if err := l.LoadPrizesCSVFile("settings/prizes.example.csv"); err != nil {
+		t.Fatalf("LoadPrizesCSVFile() error: %v", err)
+	}
+
+	// Save the data after each draw.
+	states := []map[int][]lottery.Winner{}
+	for _, prizeNo := range []int{3, 2, 1} {
+		if _, err := l.Draw(prizeNo); err != nil {
+			t.Fatalf("Draw() error: %v", err)
+		}
+		if err := l.SaveToStore(); err != nil {
+			t.Fatalf("SaveToStore() error: %v", err)
+		}
+		// Copy the winners map.
+		state := map[int][]lottery.Winner{}
+		for no, winners := range l.AllWinners() {
+			state[no] = winners
+		}
+		states = append(states, state)
+	}
+
+	backups, err := l.Backups()
+	if err != nil {
+		t.Fatalf("Backups() error: %v", err)
+	}
+	if len(backups) != 2 {
+		t.Fatalf("got %v backups, want 2", len(backups))
+	}
+
+	// The newest backup is the data before the last save.
+	if err := l.RestoreBackup(backups[0].File); err != nil {
+		t.Fatalf("RestoreBackup() error: %v", err)
+	}
+	if !reflect.DeepEqual(l.AllWinners(), states[1]) {
+		t.Errorf("winners after restore = %v, want %v", l.AllWinners(), states[1])
+	}
+
+	loaded := lottery.New("backups", lottery.WithStore(s))
+	if err := loaded.LoadFromStore(); err != nil {
+		t.Fatalf("LoadFromStore() error: %v", err)
+	}
+	if !reflect.DeepEqual(loaded.AllWinners(), states[1]) {
+		t.Errorf("loaded winners after restore = %v, want %v", loaded.AllWinners(), states[1])
+	}
+
+	if err := l.RestoreBackup("../" + backups[1].File); err != lottery.ErrBackup {
+		t.Errorf("RestoreBackup() = %v, want %v", err, lottery.ErrBackup)
+	}
+}
+
+func TestFileStoreRestoreCorruptedBackup(t *testing.T) {
+	dir := t.TempDir()
+	s := lottery.NewFileStore(dir)
+
+	l := lottery.New("corrupted", lottery.WithStore(s))
+	if err := l.LoadParticipantsCSVFile("settings/participants.example.csv"); err != nil {
+		t.Fatalf("LoadParticipantsCSVFile() error: %v", err)
+	}
+	if err := l.LoadPrizesCSVFile("settings/prizes.example.csv"); err != nil {
+		t.Fatalf("LoadPrizesCSVFile() error: %v", err)
+	}
+	for _, prizeNo := range []int{3, 2} {
+		if _, err := l.Draw(prizeNo); err != nil {
+			t.Fatalf("Draw() error: %v", err)
+		}
+		if err := l.SaveToStore(); err != nil {
+			t.Fatalf("SaveToStore() error: %v", err)
+		}
+	}
+
+	backups, err := s.Backups("corrupted")
+	if err != nil || len(backups) != 1 {
+		t.Fatalf("Backups() = %v, %v, want 1 backup", backups, err)
+	}
+
+	// Tamper the winners of the backup.
+	file := filepath.Join(dir, backups[0].File)
+	buf, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("ReadFile() error: %v", err)
+	}
+	data := lottery.SaveData{}
+	if err := json.Unmarshal(buf, &data); err != nil {
+		t.Fatalf("Unmarshal() error: %v", err)
+	}
+	data.Winners[3] = nil
+	if buf, err = json.Marshal(&data); err != nil {
+		t.Fatalf("Marshal() error: %v", err)
+	}
+	if err := os.WriteFile(file, buf, 0644); err != nil {
+		t.Fatalf("WriteFile() error: %v", err)
+	}
+
+	want := l.AllWinners()
+	if err := l.RestoreBackup(backups[0].File); err != lottery.ErrChecksum {
+		t.Errorf("RestoreBackup() = %v, want %v", err, lottery.ErrChecksum)
+	}
+	if !reflect.DeepEqual(l.AllWinners(), want) {
+		t.Errorf("winners changed after failed restore")
+	}
+}
+
+func TestFileStoreRestoreSignedV0Backup(t *testing.T) {
+	signer, _ := lottery.NewHMACSigner([]byte("secret"))
+	dir := t.TempDir()
+	s := lottery.NewFileStore(dir)
+
+	l := lottery.New("signed", lottery.WithSigner(signer), lottery.WithStore(s))
+	if err := l.LoadParticipantsCSVFile("settings/participants.example.csv"); err != nil {
+		t.Fatalf("LoadParticipantsCSVFile() error: %v", err)
+	}
+	if err := l.LoadPrizesCSVFile("settings/prizes.example.csv"); err != nil {
+		t.Fatalf("LoadPrizesCSVFile() error: %v", err)
+	}
+	if _, err := l.Draw(3); err != nil {
+		t.Fatalf("Draw() error: %v", err)
+	}
+	if err := l.SaveToStore(); err != nil {
+		t.Fatalf("SaveToStore() error: %v", err)
+	}
+	data, err := s.Load("signed")
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+
+	if _, err := l.Draw(2); err != nil {
+		t.Fatalf("Draw() error: %v", err)
+	}
+	if err := l.SaveToStore(); err != nil {
+		t.Fatalf("SaveToStore() error: %v", err)
+	}
+
+	// The backup is signed in the format of version 0.
+	backups, err := l.Backups()
+	if err != nil || len(backups) != 1 {
+		t.Fatalf("Backups() = %v, %v, want 1 backup", backups, err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, backups[0].File), marshalV0(t, data, signer), 0644); err != nil {
+		t.Fatalf("WriteFile() error: %v", err)
+	}
+
+	if err := l.RestoreBackup(backups[0].File); err != nil {
+		t.Fatalf("RestoreBackup() error: %v", err)
+	}
+	if !reflect.DeepEqual(l.AllWinners(), data.Winners) {
+		t.Errorf("winners after restore = %v, want %v", l.AllWinners(), data.Winners)
+	}
+
+	// The restored data is signed in the current format.
+	loaded := lottery.New("signed", lottery.WithSigner(signer), lottery.WithStore(s))
+	if err := loaded.LoadFromStore(); err != nil {
+		t.Fatalf("LoadFromStore() error: %v", err)
+	}
+	if !reflect.DeepEqual(loaded.AllWinners(), data.Winners) {
+		t.Errorf("loaded winners after restore = %v, want %v", loaded.AllWinners(), data.Winners)
+	}
+
+	// The store copies the backup as is and keeps its signature.
+	if err := s.RestoreBackup("signed", backups[0].File); err != nil {
+		t.Fatalf("FileStore.RestoreBackup() error: %v", err)
+	}
+	if err := lottery.New("signed", lottery.WithSigner(signer), lottery.WithStore(s)).LoadFromStore(); err != nil {
+		t.Errorf("LoadFromStore() error: %v", err)
+	}
+}
+
+func TestSaveToFileUsesStore(t *testing.T) {
+	ws := lottery.NewWorkspace(t.TempDir())
+	s := lottery.NewFileStore(t.TempDir())
+
+	l := ws.New("store", lottery.WithStore(s))
+	if err := l.LoadPrizesCSVFile("settings/prizes.example.csv"); err != nil {
+		t.Fatalf("LoadPrizesCSVFile() error: %v", err)
+	}
+	if err := l.SaveToFile(); err != nil {
+		t.Fatalf("SaveToFile() error: %v", err)
+	}
+
+	// The data is saved in the store of the lottery only.
+	if exists, err := s.Exists("store"); err != nil || !exists {
+		t.Errorf("Exists() in the store = %v, %v, want true", exists, err)
+	}
+	if exists, err := ws.Store().Exists("store"); err != nil || exists {
+		t.Errorf("Exists() in the workspace = %v, %v, want false", exists, err)
+	}
+	if !l.DataFileExists() {
+		t.Errorf("DataFileExists() = false, want true")
+	}
+	if err := ws.New("store", lottery.WithStore(s)).LoadFromFile(); err != nil {
+		t.Errorf("LoadFromFile() error: %v", err)
+	}
+}
@@ -0,0 +1,174 @@
+package lottery
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Stratum is the allocation of a prize to a stratum in a stratified draw.
+type Stratum struct {
+	// Value is the attribute value of the stratum.
+	// Empty value is the stratum of participants without the attribute.
+	Value string `json:"value"`
+	// Headcount is the amount of available participants in the stratum.
+	Headcount int `json:"headcount"`
+	// Quota is the amount of winners allocated to the stratum.
+	Quota int `json:"quota"`
+	// Winners are the winners drawn from the stratum.
+	Winners []Winner `json:"winners"`
+}
+
+var (
+	ErrStratifiedAttribute = fmt.Errorf("incorrect stratified attribute")
+)
+
+// allocate allocates the amount across the strata in proportion to their
+// headcounts with the largest remainder method.
+// The amount should not be greater than the total headcount.
+func allocate(amount int, strata []Stratum) {
+	total := 0
+	for _, s := range strata {
+		total += s.Headcount
+	}
+
+	if total == 0 {
+		return
+	}
+
+	remainders := make([]int, len(strata))
+	allocated := 0
+	for i := range strata {
+		strata[i].Quota = amount * strata[i].Headcount / total
+		remainders[i] = amount * strata[i].Headcount % total
+		allocated += strata[i].Quota
+	}
+
+	// Give the remaining seats to the strata with the largest remainders.
+	// Ties are broken by larger headcount, then by value.
+	indexes := make([]int, len(strata))
+	for i := range indexes {
+		indexes[i] = i
+	}
+
+	sort.SliceStable(indexes, func(i, j int) bool {
+		a, b := indexes[i], indexes[j]
+		if remainders[a] != remainders[b] {
+			return remainders[a] > remainders[b]
+		}
+		if strata[a].Headcount != strata[b].Headcount {
+			return strata[a].Headcount > strata[b].Headcount
+		}
+		return strata[a].Value < strata[b].Value
+	})
+
+	for i := 0; i < amount-allocated; i++ {
+		strata[indexes[i]].Quota++
+	}
+}
+
+// DrawStratified draws the prize with the amount allocated across the strata
+// of the attribute in proportion to their headcounts of available participants.
+// It uses the largest remainder method to round the quotas,
+// then draws the winners inside each stratum.
+// It returns the winners and the allocation plan sorted by attribute value.
+//
+// If a commitment is pending for the prize, each stratum is drawn with a new
+// RNG seeded with SHA-256(seed || stratum value) and records its own draw
+// record with the attribute and the stratum value.
+func (l *Lottery) DrawStratified(prizeNo int, attribute string) ([]Winner, []Stratum, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	winners := []Winner{}
+	strata := []Stratum{}
+
+	if _, ok := l.prizes[prizeNo]; !ok {
+		return winners, strata, ErrPrizeNo
+	}
+
+	amount := l.prizes[prizeNo].Amount
+	if amount < 1 {
+		return winners, strata, ErrPrizeAmount
+	}
+
+	if attribute == "" {
+		return winners, strata, ErrStratifiedAttribute
+	}
+
+	if _, ok := l.winners[prizeNo]; ok {
+		return winners, strata, ErrWinnersExistBeforeDraw
+	}
+
+	participants := l.availableParticipants(prizeNo)
+	if len(participants) == 0 {
+		return winners, strata, ErrNoAvailableParticipants
+	}
+
+	if amount > len(participants) {
+		amount = len(participants)
+	}
+
+	caps := l.groupCaps(prizeNo, participants)
+	if err := checkGroupCaps(prizeNo, amount, participants, caps); err != nil {
+		return winners, strata, err
+	}
+
+	// Split the participants into strata.
+	pools := make(map[string][]Participant)
+	for _, p := range participants {
+		v := p.Attributes[attribute]
+		pools[v] = append(pools[v], p)
+	}
+
+	for v, pool := range pools {
+		strata = append(strata, Stratum{v, len(pool), 0, []Winner{}})
+	}
+
+	sort.Slice(strata, func(i, j int) bool {
+		return strata[i].Value < strata[j].Value
+	})
+
+	allocate(amount, strata)
+
+	r := l.rollbackPoint(prizeNo)
+
+	drawn := []Participant{}
+	records := []DrawRecord{}
+	for i := range strata {
+		if strata[i].Quota == 0 {
+			continue
+		}
+
+		w, record, err := l.drawWithCaps(prizeNo, strata[i].Quota, 0, pools[strata[i].Value], caps, attribute, strata[i].Value)
+		if err != nil {
+			return []Winner{}, []Stratum{}, err
+		}
+
+		drawn = append(drawn, w...)
+		if record != nil {
+			records = append(records, *record)
+		}
+	}
+
+	if _, ok := l.seeds[prizeNo]; ok {
+		l.drawRecords[prizeNo] = append(l.drawRecords[prizeNo], records...)
+		delete(l.seeds, prizeNo)
+	}
+
+	l.winners[prizeNo] = []Winner{}
+	winners = l.addWinners(prizeNo, drawn, l.nextRound(prizeNo))
+
+	for i := range strata {
+		for _, w := range winners {
+			if w.Attributes[attribute] == strata[i].Value {
+				strata[i].Winners = append(strata[i].Winners, w)
+			}
+		}
+	}
+
+	if err := l.recordPrize(Event{Type: EventDrawStratified, PrizeNo: prizeNo, Attribute: attribute, Meta: l.meta}, r); err != nil {
+		return []Winner{}, []Stratum{}, err
+	}
+
+	return winners, strata, nil
+}
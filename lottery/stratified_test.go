@@ -0,0 +1,91 @@
+package lottery_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/northbright/lottery-go/lottery"
+)
+
+func TestDrawStratified(t *testing.T) {
+	// Engineering: 8, Sales: 5, HR: 2. Total: 15.
+	var b strings.Builder
+	b.WriteString("ID,Name,Dept\n")
+	depts := []struct {
+		name  string
+		count int
+	}{{"Engineering", 8}, {"Sales", 5}, {"HR", 2}}
+
+	id := 0
+	for _, d := range depts {
+		for i := 0; i < d.count; i++ {
+			id++
+			fmt.Fprintf(&b, "%v,P%v,%v\n", id, id, d.name)
+		}
+	}
+
+	l := lottery.New("stratified", lottery.WithRNG(lottery.NewSeededRNG([]byte("strata"))))
+	if err := l.LoadParticipantsCSV(strings.NewReader(b.String())); err != nil {
+		t.Fatalf("LoadParticipantsCSV() error: %v", err)
+	}
+	l.SetPrize(5, "5th prize", 6, "")
+
+	winners, strata, err := l.DrawStratified(5, "Dept")
+	if err != nil {
+		t.Fatalf("DrawStratified() error: %v", err)
+	}
+
+	// Quotas: Engineering 6*8/15 = 3.2, Sales 2.0, HR 0.8.
+	// Largest remainder: Engineering 3, Sales 2, HR 1.
+	want := map[string]int{"Engineering": 3, "Sales": 2, "HR": 1}
+	for _, s := range strata {
+		if s.Quota != want[s.Value] {
+			t.Errorf("quota of %v = %v, want %v", s.Value, s.Quota, want[s.Value])
+		}
+		if len(s.Winners) != s.Quota {
+			t.Errorf("%v has %v winners, want %v", s.Value, len(s.Winners), s.Quota)
+		}
+		for _, w := range s.Winners {
+			if w.Attributes["Dept"] != s.Value {
+				t.Errorf("winner %v is not in stratum %v", w, s.Value)
+			}
+		}
+	}
+
+	if len(winners) != 6 {
+		t.Errorf("got %v winners, want 6", len(winners))
+	}
+}
+
+func TestDrawStratifiedCommitted(t *testing.T) {
+	csv := "ID,Name,Dept\n1,A,Sales\n2,B,Sales\n3,C,Sales\n4,D,HR\n5,E,HR\n6,F,HR\n"
+
+	l := lottery.New("stratified")
+	if err := l.LoadParticipantsCSV(strings.NewReader(csv)); err != nil {
+		t.Fatalf("LoadParticipantsCSV() error: %v", err)
+	}
+	l.SetPrize(1, "1st prize", 2, "")
+
+	if _, err := l.Commit(1); err != nil {
+		t.Fatalf("Commit() error: %v", err)
+	}
+	if _, _, err := l.DrawStratified(1, "Dept"); err != nil {
+		t.Fatalf("DrawStratified() error: %v", err)
+	}
+
+	// Each stratum records its own stratum value to derive the seed.
+	records := l.DrawRecords(1)
+	if len(records) != 2 || records[0].Stratum != "HR" || records[1].Stratum != "Sales" {
+		t.Fatalf("draw records = %+v, want HR and Sales", records)
+	}
+	for _, r := range records {
+		if r.Attribute != "Dept" {
+			t.Errorf("attribute = %q, want Dept", r.Attribute)
+		}
+		if err := lottery.Verify(r); err != nil {
+			t.Errorf("Verify(%v) error: %v", r.Stratum, err)
+		}
+	}
+
+}
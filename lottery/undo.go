@@ -0,0 +1,210 @@
+package lottery
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// UndoStep is an operation which can be undone or redone.
+type UndoStep struct {
+	// Type is the event type of the operation.
+	// It's one of EventDraw, EventRevoke, EventRedraw and EventClearWinners.
+	Type    string `json:"type"`
+	PrizeNo int    `json:"prize_no"`
+	Time    string `json:"time"`
+}
+
+// undoEntry is an undo step with the states of the prize before and after
+// the operation.
+type undoEntry struct {
+	step   UndoStep
+	before PrizeState
+	after  PrizeState
+}
+
+const (
+	// UndoLimit is the max amount of operations which can be undone.
+	UndoLimit = 20
+)
+
+var (
+	ErrNothingToUndo = fmt.Errorf("nothing to undo")
+	ErrNothingToRedo = fmt.Errorf("nothing to redo")
+	ErrFinalized     = fmt.Errorf("lottery is finalized")
+	ErrUndoConflict  = fmt.Errorf("prize is changed after the operation")
+)
+
+// pushUndo pushes the undoable operation of the prize to the undo history
+// and clears the redo history.
+func (l *Lottery) pushUndo(eventType string, prizeNo int, before PrizeState) {
+	step := UndoStep{eventType, prizeNo, time.Now().Format("2006-01-02 15:04:05")}
+	l.undos = append(l.undos, undoEntry{step, before, l.prizeState(prizeNo)})
+
+	if len(l.undos) > UndoLimit {
+		l.undos = l.undos[len(l.undos)-UndoLimit:]
+	}
+	l.redos = nil
+}
+
+// clearUndo clears the undo and redo history.
+// It's called after the winners are changed by operations which can not
+// be undone, so the earlier operations can not be undone either.
+func (l *Lottery) clearUndo() {
+	l.undos = nil
+	l.redos = nil
+}
+
+// undoable reports whether the operation of the event type can be undone.
+func undoable(eventType string) bool {
+	switch eventType {
+	case EventDraw, EventRevoke, EventRedraw, EventClearWinners:
+		return true
+	default:
+		return false
+	}
+}
+
+// Undo undoes the last Draw, Revoke, Redraw or ClearWinners and restores
+// the state of the prize before the operation.
+// Operations can be undone in reverse order up to UndoLimit.
+// Other operations which change the winners(e.g. Confirm, Replace)
+// clear the undo history.
+// Commit-reveal draws can not be undone, because their draw records must be
+// kept. It returns ErrUndoConflict for them.
+// It returns ErrFinalized after Finalize is called.
+// The undo history is kept in memory only.
+func (l *Lottery) Undo() (UndoStep, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.finalized {
+		return UndoStep{}, ErrFinalized
+	}
+
+	if len(l.undos) == 0 {
+		return UndoStep{}, ErrNothingToUndo
+	}
+
+	entry := l.undos[len(l.undos)-1]
+	if !reflect.DeepEqual(l.prizeState(entry.step.PrizeNo), entry.after) {
+		return UndoStep{}, ErrUndoConflict
+	}
+
+	// The revealed seed and the published commitment can not be taken back.
+	if len(entry.after.DrawRecords) > len(entry.before.DrawRecords) {
+		return UndoStep{}, ErrUndoConflict
+	}
+
+	r := l.rollbackPoint(entry.step.PrizeNo)
+
+	l.undos = l.undos[:len(l.undos)-1]
+	l.redos = append(l.redos, entry)
+	l.restorePrizeState(entry.step.PrizeNo, entry.before)
+
+	if err := l.recordPrize(Event{Type: EventUndo, PrizeNo: entry.step.PrizeNo, Reason: entry.step.Type, Meta: l.meta}, r); err != nil {
+		return UndoStep{}, err
+	}
+
+	return entry.step, nil
+}
+
+// Redo redoes the last undone operation and restores the state of the
+// prize after the operation.
+func (l *Lottery) Redo() (UndoStep, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.finalized {
+		return UndoStep{}, ErrFinalized
+	}
+
+	if len(l.redos) == 0 {
+		return UndoStep{}, ErrNothingToRedo
+	}
+
+	entry := l.redos[len(l.redos)-1]
+	if !reflect.DeepEqual(l.prizeState(entry.step.PrizeNo), entry.before) {
+		return UndoStep{}, ErrUndoConflict
+	}
+
+	r := l.rollbackPoint(entry.step.PrizeNo)
+
+	l.redos = l.redos[:len(l.redos)-1]
+	l.undos = append(l.undos, entry)
+	l.restorePrizeState(entry.step.PrizeNo, entry.after)
+
+	if err := l.recordPrize(Event{Type: EventRedo, PrizeNo: entry.step.PrizeNo, Reason: entry.step.Type, Meta: l.meta}, r); err != nil {
+		return UndoStep{}, err
+	}
+
+	return entry.step, nil
+}
+
+// UndoSteps returns the operations which can be undone.
+// The last one is undone first.
+func (l *Lottery) UndoSteps() []UndoStep {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	steps := []UndoStep{}
+	for _, entry := range l.undos {
+		steps = append(steps, entry.step)
+	}
+
+	return steps
+}
+
+// RedoSteps returns the undone operations which can be redone.
+// The last one is redone first.
+func (l *Lottery) RedoSteps() []UndoStep {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	steps := []UndoStep{}
+	for _, entry := range l.redos {
+		steps = append(steps, entry.step)
+	}
+
+	return steps
+}
+
+// restorePrizeState restores the state of the prize and restarts the claim
+// timers of its pending winners.
+func (l *Lottery) restorePrizeState(prizeNo int, s PrizeState) {
+	for ID := range l.claimTimers[prizeNo] {
+		l.stopClaimTimer(prizeNo, ID)
+	}
+
+	l.setPrizeState(prizeNo, copyPrizeState(s))
+
+	for _, winner := range l.winners[prizeNo] {
+		if l.claimStatus(prizeNo, winner.ID) == ClaimPending {
+			l.startClaimTimer(prizeNo, winner.ID)
+		}
+	}
+}
+
+// Finalize finalizes the lottery. Operations can not be undone or redone
+// after the lottery is finalized.
+func (l *Lottery) Finalize() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if err := l.record(Event{Type: EventFinalize, Meta: l.meta}); err != nil {
+		return err
+	}
+
+	l.finalized = true
+	l.clearUndo()
+
+	return nil
+}
+
+// Finalized reports whether the lottery is finalized.
+func (l *Lottery) Finalized() bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return l.finalized
+}
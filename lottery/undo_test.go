@@ -0,0 +1,144 @@
+package lottery_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/northbright/lottery-go/lottery"
+)
+
+func TestUndoRedo(t *testing.T) {
+	l := newSeededLottery(t, "undo")
+
+	winners, err := l.Draw(3)
+	if err != nil {
+		t.Fatalf("Draw() error: %v", err)
+	}
+	if err := l.Revoke(3, []lottery.Participant{winners[0].Participant}, lottery.ReasonAbsent); err != nil {
+		t.Fatalf("Revoke() error: %v", err)
+	}
+	revoked := l.Winners(3)
+
+	if _, err := l.Redraw(3, 1); err != nil {
+		t.Fatalf("Redraw() error: %v", err)
+	}
+	redrawn := l.Winners(3)
+
+	if steps := l.UndoSteps(); len(steps) != 3 || steps[2].Type != lottery.EventRedraw {
+		t.Fatalf("UndoSteps() = %v, want draw, revoke and redraw", steps)
+	}
+
+	// Undo the redraw and the revoke.
+	for _, want := range []string{lottery.EventRedraw, lottery.EventRevoke} {
+		step, err := l.Undo()
+		if err != nil {
+			t.Fatalf("Undo() error: %v", err)
+		}
+		if step.Type != want || step.PrizeNo != 3 {
+			t.Errorf("Undo() = %v, want %v of prize 3", step, want)
+		}
+	}
+
+	if !reflect.DeepEqual(l.Winners(3), winners) {
+		t.Errorf("winners after undo = %v, want %v", l.Winners(3), winners)
+	}
+	if n := len(l.Revocations(3)); n != 0 {
+		t.Errorf("got %v revocations after undo, want 0", n)
+	}
+
+	// Redo the revoke.
+	if _, err := l.Redo(); err != nil {
+		t.Fatalf("Redo() error: %v", err)
+	}
+	if !reflect.DeepEqual(l.Winners(3), revoked) {
+		t.Errorf("winners after redo = %v, want %v", l.Winners(3), revoked)
+	}
+
+	if _, err := l.Redo(); err != nil {
+		t.Fatalf("Redo() error: %v", err)
+	}
+	if !reflect.DeepEqual(l.Winners(3), redrawn) {
+		t.Errorf("winners after redo = %v, want %v", l.Winners(3), redrawn)
+	}
+	if _, err := l.Redo(); err != lottery.ErrNothingToRedo {
+		t.Errorf("Redo() = %v, want %v", err, lottery.ErrNothingToRedo)
+	}
+
+	// Undo the clear.
+	l.ClearWinners(3)
+	if _, err := l.Undo(); err != nil {
+		t.Fatalf("Undo() error: %v", err)
+	}
+	if !reflect.DeepEqual(l.Winners(3), redrawn) {
+		t.Errorf("winners after undo clear = %v, want %v", l.Winners(3), redrawn)
+	}
+
+	// Undo all operations: the prize can be drawn again.
+	for len(l.UndoSteps()) > 0 {
+		if _, err := l.Undo(); err != nil {
+			t.Fatalf("Undo() error: %v", err)
+		}
+	}
+	if _, err := l.Draw(3); err != nil {
+		t.Errorf("Draw() after undo error: %v", err)
+	}
+}
+
+func TestUndoGuards(t *testing.T) {
+	l := newSeededLottery(t, "undo")
+
+	if _, err := l.Undo(); err != lottery.ErrNothingToUndo {
+		t.Errorf("Undo() = %v, want %v", err, lottery.ErrNothingToUndo)
+	}
+
+	winners, err := l.Draw(2)
+	if err != nil {
+		t.Fatalf("Draw() error: %v", err)
+	}
+
+	// Operations which can not be undone clear the undo history.
+	if err := l.Confirm(2, winners[0].ID); err != nil {
+		t.Fatalf("Confirm() error: %v", err)
+	}
+	if _, err := l.Undo(); err != lottery.ErrNothingToUndo {
+		t.Errorf("Undo() after Confirm = %v, want %v", err, lottery.ErrNothingToUndo)
+	}
+
+	if _, err := l.Draw(1); err != nil {
+		t.Fatalf("Draw() error: %v", err)
+	}
+	if err := l.Finalize(); err != nil {
+		t.Fatalf("Finalize() error: %v", err)
+	}
+	if _, err := l.Undo(); err != lottery.ErrFinalized {
+		t.Errorf("Undo() after Finalize = %v, want %v", err, lottery.ErrFinalized)
+	}
+	if !l.Finalized() {
+		t.Errorf("Finalized() = false, want true")
+	}
+}
+
+func TestUndoCommittedDraw(t *testing.T) {
+	l := newSeededLottery(t, "undo")
+
+	commitment, err := l.Commit(3)
+	if err != nil {
+		t.Fatalf("Commit() error: %v", err)
+	}
+	winners, err := l.Draw(3)
+	if err != nil {
+		t.Fatalf("Draw() error: %v", err)
+	}
+
+	// The draw record of the revealed seed is kept.
+	if _, err := l.Undo(); err != lottery.ErrUndoConflict {
+		t.Errorf("Undo() committed draw = %v, want %v", err, lottery.ErrUndoConflict)
+	}
+	records := l.DrawRecords(3)
+	if len(records) != 1 || records[0].Commitment != commitment {
+		t.Errorf("draw records = %v, want the record of %v", records, commitment)
+	}
+	if w := l.Winners(3); len(w) != len(winners) {
+		t.Errorf("winners = %v, want %v", w, winners)
+	}
+}
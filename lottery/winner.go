@@ -0,0 +1,119 @@
+package lottery
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+// Winner is a winner of a prize.
+// It embeds the participant, so winners saved as participants by old
+// versions can be loaded as winners.
+type Winner struct {
+	Participant
+	// DrawnAt is the time when the winner was drawn.
+	DrawnAt string `json:"drawn_at,omitempty"`
+	// Round is the round number of the draw of the prize.
+	Round int `json:"round,omitempty"`
+	// OperationID is the ID of the operation which drew the winner.
+	// Winners drawn by the same operation have the same ID.
+	OperationID string `json:"operation_id,omitempty"`
+	// Position is the 1-based position of the winner within the prize.
+	Position int `json:"position,omitempty"`
+	// Replaces is the ID of the revoked or forfeited winner
+	// replaced by the winner.
+	Replaces string `json:"replaces,omitempty"`
+}
+
+func newOperationID() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		// Fall back to the current time.
+		return time.Now().Format("20060102150405.000000000")
+	}
+	return hex.EncodeToString(buf)
+}
+
+// newWinners returns the winners drawn from the participants by the same
+// operation in the round.
+func newWinners(participants []Participant, round int) []Winner {
+	winners := []Winner{}
+	ID := newOperationID()
+	drawnAt := time.Now().Format("2006-01-02 15:04:05")
+
+	for _, p := range participants {
+		winners = append(winners, Winner{p, drawnAt, round, ID, 0, ""})
+	}
+
+	return winners
+}
+
+func winnerParticipants(winners []Winner) []Participant {
+	participants := []Participant{}
+
+	for _, w := range winners {
+		participants = append(participants, w.Participant)
+	}
+
+	return participants
+}
+
+// winnerIndex returns the index of the winner in the winners of the prize.
+// It returns -1 if the participant is not a winner of the prize.
+func (l *Lottery) winnerIndex(prizeNo int, ID string) int {
+	for i, winner := range l.winners[prizeNo] {
+		if winner.ID == ID {
+			return i
+		}
+	}
+	return -1
+}
+
+// updatePositions updates the positions of the winners of the prize.
+func (l *Lottery) updatePositions(prizeNo int) {
+	for i := range l.winners[prizeNo] {
+		l.winners[prizeNo][i].Position = i + 1
+	}
+}
+
+// addWinners appends the winners drawn from the participants in the round
+// to the winners of the prize. It returns the new winners.
+func (l *Lottery) addWinners(prizeNo int, participants []Participant, round int) []Winner {
+	winners := newWinners(participants, round)
+	n := len(l.winners[prizeNo])
+
+	l.winners[prizeNo] = append(l.winners[prizeNo], winners...)
+	l.updatePositions(prizeNo)
+
+	winners = l.winners[prizeNo][n:]
+	l.markDrawn(prizeNo, winners)
+	return append([]Winner{}, winners...)
+}
+
+// insertWinner inserts the participant at the index of the winners of the
+// prize to replace the removed winner. The new winner keeps the round of
+// the removed one.
+func (l *Lottery) insertWinner(prizeNo int, index int, p Participant, removed Winner) Winner {
+	round := removed.Round
+	if round == 0 {
+		round = l.nextRound(prizeNo)
+	}
+
+	winner := newWinners([]Participant{p}, round)[0]
+	winner.Replaces = removed.ID
+
+	winners := l.winners[prizeNo]
+	if index < 0 || index > len(winners) {
+		index = len(winners)
+	}
+
+	winners = append(winners, Winner{})
+	copy(winners[index+1:], winners[index:])
+	winners[index] = winner
+	l.winners[prizeNo] = winners
+	l.updatePositions(prizeNo)
+
+	winner = winners[index]
+	l.markDrawn(prizeNo, []Winner{winner})
+	return winner
+}
@@ -0,0 +1,135 @@
+package lottery_test
+
+import (
+	"bytes"
+	"crypto/md5"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/northbright/lottery-go/lottery"
+)
+
+func TestWinnerRecords(t *testing.T) {
+	l := newSeededLottery(t, "winners")
+
+	first, _, err := l.DrawBatch(4, 3)
+	if err != nil {
+		t.Fatalf("DrawBatch() error: %v", err)
+	}
+	second, _, err := l.DrawBatch(4, 2)
+	if err != nil {
+		t.Fatalf("DrawBatch() error: %v", err)
+	}
+
+	for i, w := range append(first, second...) {
+		if w.Position != i+1 {
+			t.Errorf("winner %v: position = %v, want %v", w.ID, w.Position, i+1)
+		}
+		if w.DrawnAt == "" || w.OperationID == "" {
+			t.Errorf("winner %v: draw time or operation ID is empty", w.ID)
+		}
+	}
+
+	if first[0].Round != 1 || second[0].Round != 2 {
+		t.Errorf("rounds = %v, %v, want 1, 2", first[0].Round, second[0].Round)
+	}
+	if first[0].OperationID != first[2].OperationID || first[0].OperationID == second[0].OperationID {
+		t.Errorf("winners of the same batch should share the operation ID")
+	}
+
+	// Revoke the 2nd winner: positions of the later winners move forward.
+	if err := l.Revoke(4, []lottery.Participant{first[1].Participant}, lottery.ReasonMistake); err != nil {
+		t.Fatalf("Revoke() error: %v", err)
+	}
+	winners := l.Winners(4)
+	if winners[1].ID != first[2].ID || winners[1].Position != 2 {
+		t.Errorf("winner at position 2 = %v, want %v", winners[1], first[2].ID)
+	}
+
+	// Draw from prize 3 with an alternate and promote it in place.
+	if err := l.SetPrizeAlternates(3, 1); err != nil {
+		t.Fatalf("SetPrizeAlternates() error: %v", err)
+	}
+	drawn, err := l.Draw(3)
+	if err != nil {
+		t.Fatalf("Draw() error: %v", err)
+	}
+
+	promoted, err := l.PromoteAlternate(3, drawn[1].Participant, lottery.ReasonMistake)
+	if err != nil {
+		t.Fatalf("PromoteAlternate() error: %v", err)
+	}
+	if promoted.Replaces != drawn[1].ID || promoted.Position != 2 || promoted.Round != drawn[1].Round {
+		t.Errorf("promoted alternate = %+v, want replaces %v at position 2", promoted, drawn[1].ID)
+	}
+}
+
+func TestLoadOldWinners(t *testing.T) {
+	// Old versions save winners as participants and rounds in a separated map.
+	checksum := fmt.Sprintf("%X", md5.Sum([]byte("1"+"1"+"Alice"+"2"+"Bob")))
+	data := `{
+    "name": "old",
+    "prizes": {"1": {"no": 1, "name": "Grand", "amount": 2, "desc": ""}},
+    "blacklists": {},
+    "participants": {
+        "1": {"id": "1", "name": "Alice"},
+        "2": {"id": "2", "name": "Bob"}
+    },
+    "winners": {"1": [{"id": "1", "name": "Alice"}, {"id": "2", "name": "Bob"}]},
+    "rounds": {"1": {"1": 1, "2": 2}},
+    "last_updated": "2020-01-01 00:00:00",
+    "checksum": "` + checksum + `"
+}`
+
+	l := lottery.New("old")
+	if err := l.Load(strings.NewReader(data)); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+
+	winners := l.Winners(1)
+	if len(winners) != 2 {
+		t.Fatalf("got %v winners, want 2", len(winners))
+	}
+	for i, w := range winners {
+		if w.Position != i+1 || w.Round != i+1 {
+			t.Errorf("winner %v: position = %v, round = %v, want %v", w.ID, w.Position, w.Round, i+1)
+		}
+	}
+	if round := l.WinnerRound(1, "2"); round != 2 {
+		t.Errorf("WinnerRound() = %v, want 2", round)
+	}
+}
+
+func TestRoundsNotReused(t *testing.T) {
+	l := newSeededLottery(t, "rounds")
+
+	if _, _, err := l.DrawBatch(4, 2); err != nil {
+		t.Fatalf("DrawBatch() error: %v", err)
+	}
+	second, _, err := l.DrawBatch(4, 2)
+	if err != nil {
+		t.Fatalf("DrawBatch() error: %v", err)
+	}
+
+	// Revoke all winners of the last round.
+	if err := l.Revoke(4, participants(second), lottery.ReasonMistake); err != nil {
+		t.Fatalf("Revoke() error: %v", err)
+	}
+
+	// The round counter is saved with the lottery.
+	buf := &bytes.Buffer{}
+	if err := l.Save(buf); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+	loaded := lottery.New("rounds")
+	if err := loaded.Load(buf); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+
+	for _, l := range []*lottery.Lottery{l, loaded} {
+		if _, round, err := l.DrawBatch(4, 1); err != nil || round != 3 {
+			t.Errorf("DrawBatch() = %v, %v, want round 3", round, err)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package lottery
+
+import (
+	"crypto/md5"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Workspace is the data dir of lotteries.
+// The saved data files and journals of the lotteries are in the dir.
+// The dir is created when data is saved. Independent workspaces can be used
+// in one process.
+type Workspace struct {
+	dir   string
+	store *FileStore
+	// archived is the workspace of the archived lotteries.
+	// It's nil if the workspace is the archive.
+	archived *Workspace
+	mutex    *sync.Mutex
+}
+
+// NewWorkspace returns a workspace in the dir.
+// Archived lotteries are in the ArchiveDir sub dir.
+func NewWorkspace(dir string) *Workspace {
+	archiveDir := filepath.Join(dir, ArchiveDir)
+	archived := &Workspace{archiveDir, NewFileStore(archiveDir), nil, &sync.Mutex{}}
+	return &Workspace{dir, NewFileStore(dir), archived, &sync.Mutex{}}
+}
+
+// WithWorkspace sets the workspace of the lottery.
+// It also sets the store of the lottery to the store of the workspace.
+// The default workspace is NewWorkspace(AppDataDir).
+func WithWorkspace(w *Workspace) Option {
+	return func(l *Lottery) {
+		l.workspace = w
+		l.store = w.store
+	}
+}
+
+// Dir returns the data dir of the workspace.
+func (w *Workspace) Dir() string {
+	return w.dir
+}
+
+// Init creates the data dir of the workspace if it does not exist.
+func (w *Workspace) Init() error {
+	return os.MkdirAll(w.dir, 0755)
+}
+
+// Store returns the JSON file store of the workspace.
+func (w *Workspace) Store() *FileStore {
+	return w.store
+}
+
+// JournalFile returns the journal file of the lottery in the workspace.
+func (w *Workspace) JournalFile(name string) string {
+	f := fmt.Sprintf("%X.journal", md5.Sum([]byte(name)))
+	return filepath.Join(w.dir, f)
+}
+
+// New creates a lottery in the workspace.
+// Options are applied after the workspace is set, so WithStore overrides the
+// store of the workspace.
+func (w *Workspace) New(name string, options ...Option) *Lottery {
+	return New(name, append([]Option{WithWorkspace(w)}, options...)...)
+}
+
+// defaultAppDataDir returns "<home dir>/lottery-go".
+// It returns AppName as a relative dir if the home dir is unknown.
+func defaultAppDataDir() string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return AppName
+	}
+	return filepath.Join(homeDir, AppName)
+}
@@ -0,0 +1,51 @@
+package lottery_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/northbright/lottery-go/lottery"
+)
+
+func TestWorkspace(t *testing.T) {
+	// The dirs are created when data is saved.
+	ws1 := lottery.NewWorkspace(filepath.Join(t.TempDir(), "ws1"))
+	ws2 := lottery.NewWorkspace(filepath.Join(t.TempDir(), "ws2"))
+
+	l1 := ws1.New("party")
+	if err := l1.LoadPrizesCSVFile("settings/prizes.example.csv"); err != nil {
+		t.Fatalf("LoadPrizesCSVFile() error: %v", err)
+	}
+	if err := l1.SaveToFile(); err != nil {
+		t.Fatalf("SaveToFile() error: %v", err)
+	}
+	if _, err := os.Stat(ws1.Store().File("party")); err != nil {
+		t.Errorf("data file error: %v", err)
+	}
+
+	// The lottery with the same name in another workspace is independent.
+	l2 := ws2.New("party")
+	if l2.DataFileExists() {
+		t.Errorf("DataFileExists() in ws2 = true, want false")
+	}
+	if err := l2.LoadFromFile(); err != lottery.ErrNotSaved {
+		t.Errorf("LoadFromFile() in ws2 = %v, want %v", err, lottery.ErrNotSaved)
+	}
+
+	if err := l2.OpenJournal(l2.JournalFile()); err != nil {
+		t.Fatalf("OpenJournal() error: %v", err)
+	}
+	defer l2.CloseJournal()
+	if filepath.Dir(l2.JournalFile()) != ws2.Dir() {
+		t.Errorf("JournalFile() = %v, want in %v", l2.JournalFile(), ws2.Dir())
+	}
+
+	loaded := ws1.New("party")
+	if err := loaded.LoadFromFile(); err != nil {
+		t.Fatalf("LoadFromFile() in ws1 error: %v", err)
+	}
+	if len(loaded.Prizes(false)) != len(l1.Prizes(false)) {
+		t.Errorf("loaded prizes = %v, want %v", loaded.Prizes(false), l1.Prizes(false))
+	}
+}